@@ -4,6 +4,5 @@ go 1.12
 
 require (
 	github.com/go-xmlfmt/xmlfmt v0.0.0-20191208150333-d5b6f63a941b
-	github.com/mpl/scgiclient v0.0.0-20171022154509-88aedc8df75e
 	github.com/stretchr/testify v1.4.0
 )
@@ -0,0 +1,190 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Identity is authenticated caller.
+type Identity struct {
+	Name  string
+	Roles []string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns copy of ctx which carries identity of caller.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns identity of caller, it's nil if caller isn't authenticated.
+func IdentityFrom(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// Credentials are data of request checked by authenticators.
+type Credentials struct {
+	// Header is header of HTTP request, for SCGI it's built from HTTP_* variables, e.g. HTTP_AUTHORIZATION.
+	Header http.Header
+	// RemoteUser is user authenticated by web server in front of SCGI server, e.g. by nginx.
+	// It's always empty for HTTP requests.
+	RemoteUser string
+}
+
+// Authenticator identifies caller by credentials. It returns nil identity and nil error if credentials don't
+// contain data it checks, so the next authenticator is tried. Error means the credentials are invalid, such
+// requests are rejected with 401. If error has method Challenge() string, its result is sent in header
+// WWW-Authenticate of HTTP response, otherwise challenge of Basic authentication is sent.
+type Authenticator func(c *Credentials) (*Identity, error)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+const basicChallenge = `Basic realm="xmlrpc", charset="UTF-8"`
+
+// invalidCredentials is errInvalidCredentials with challenge of the authentication scheme.
+type invalidCredentials struct {
+	challenge string
+}
+
+func (e *invalidCredentials) Error() string {
+	return errInvalidCredentials.Error()
+}
+
+func (e *invalidCredentials) Challenge() string {
+	return e.challenge
+}
+
+// challengeOf returns challenge sent with response 401 to request rejected with err, see Authenticator.
+func challengeOf(err error) string {
+	if c, ok := err.(interface{ Challenge() string }); ok {
+		return c.Challenge()
+	}
+	return basicChallenge
+}
+
+// BasicAuth authenticates caller with HTTP Basic authentication, check returns nil if user or password is wrong.
+func BasicAuth(check func(user, password string) *Identity) Authenticator {
+	return func(c *Credentials) (*Identity, error) {
+		user, password, ok := (&http.Request{Header: c.Header}).BasicAuth()
+		if !ok {
+			return nil, nil
+		}
+		if id := check(user, password); id != nil {
+			return id, nil
+		}
+		return nil, &invalidCredentials{basicChallenge}
+	}
+}
+
+// BearerAuth authenticates caller with bearer token from header Authorization, check returns nil if token
+// is wrong.
+func BearerAuth(check func(token string) *Identity) Authenticator {
+	return func(c *Credentials) (*Identity, error) {
+		auth := c.Header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			return nil, nil
+		}
+		if id := check(strings.TrimSpace(auth[7:])); id != nil {
+			return id, nil
+		}
+		return nil, &invalidCredentials{`Bearer realm="xmlrpc", error="invalid_token"`}
+	}
+}
+
+// RemoteUserAuth trusts user authenticated by web server in front of SCGI server, see Credentials.RemoteUser.
+// identify returns identity of user or nil if user isn't allowed, if identify is nil identity has only name.
+func RemoteUserAuth(identify func(user string) *Identity) Authenticator {
+	return func(c *Credentials) (*Identity, error) {
+		if c.RemoteUser == "" {
+			return nil, nil
+		}
+		if identify == nil {
+			return &Identity{Name: c.RemoteUser}, nil
+		}
+		if id := identify(c.RemoteUser); id != nil {
+			return id, nil
+		}
+		return nil, errInvalidCredentials
+	}
+}
+
+// authenticate adds identity of caller to ctx, caller without credentials is anonymous.
+func (s *Server) authenticate(ctx context.Context, c *Credentials) (context.Context, error) {
+	for _, auth := range s.Authenticators {
+		id, err := auth(c)
+		if err != nil {
+			return nil, err
+		}
+		if id != nil {
+			return WithIdentity(ctx, id), nil
+		}
+	}
+	return ctx, nil
+}
+
+// ACL is list of rules which allow calls of methods, call which isn't allowed by any rule is denied with
+// fault AccessDenied.
+type ACL []ACLRule
+
+// ACLRule allows identities with the given names or roles to call methods which names match the patterns.
+type ACLRule struct {
+	// Identities are names of identities, "*" means any authenticated caller.
+	Identities []string
+	Roles      []string
+	// Anonymous means the rule applies to callers which aren't authenticated.
+	Anonymous bool
+	// Methods are patterns of method names as in path.Match, e.g. "d.*" or "system.listMethods".
+	Methods []string
+}
+
+// allows checks if caller with identity id, nil for anonymous caller, may call method.
+func (acl ACL) allows(id *Identity, method string) bool {
+	for _, r := range acl {
+		if r.appliesTo(id) && r.matches(method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ACLRule) appliesTo(id *Identity) bool {
+	if id == nil {
+		return r.Anonymous
+	}
+	for _, n := range r.Identities {
+		if n == "*" || n == id.Name {
+			return true
+		}
+	}
+	for _, role := range r.Roles {
+		for _, idRole := range id.Roles {
+			if role == idRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *ACLRule) matches(method string) bool {
+	for _, p := range r.Methods {
+		if ok, _ := path.Match(p, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks access of caller to method if server has ACL.
+func (s *Server) authorize(ctx context.Context, method string) error {
+	if s.ACL == nil || s.ACL.allows(IdentityFrom(ctx), method) {
+		return nil
+	}
+	return NewFault(AccessDenied, fmt.Sprintf("access denied: %s", method))
+}
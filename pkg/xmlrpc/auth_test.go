@@ -0,0 +1,207 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var users = map[string]*Identity{
+	"admin": {Name: "admin", Roles: []string{"admin"}},
+	"joe":   {Name: "joe", Roles: []string{"viewer"}},
+}
+
+func authServer(t *testing.T) *Server {
+	whoami := func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return IdentityFrom(ctx).Name, nil
+	}
+	s := newTestServer(t, map[string]Handler{"d.name": whoami, "d.erase": whoami})
+	s.Authenticators = []Authenticator{
+		BasicAuth(func(user, password string) *Identity {
+			if password == "secret" {
+				return users[user]
+			}
+			return nil
+		}),
+		BearerAuth(func(token string) *Identity {
+			if token == "t0ken" {
+				return users["admin"]
+			}
+			return nil
+		}),
+		RemoteUserAuth(func(user string) *Identity {
+			return users[user]
+		}),
+	}
+	s.ACL = ACL{
+		{Roles: []string{"admin"}, Methods: []string{"*"}},
+		{Roles: []string{"viewer"}, Methods: []string{"d.name", "system.*"}},
+		{Anonymous: true, Methods: []string{"system.listMethods"}},
+	}
+	return s
+}
+
+func postCall(t *testing.T, s *Server, method string,
+	auth func(r *http.Request)) (*httptest.ResponseRecorder, []interface{}, error) {
+	call := `<?xml version="1.0"?><methodCall><methodName>` + method + `</methodName></methodCall>`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(call))
+	req.Header.Set("Content-Type", "text/xml")
+	if auth != nil {
+		auth(req)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec, nil, nil
+	}
+	res, err := NewDecoder(rec.Body).DecodeResponse()
+	return rec, res, err
+}
+
+func TestHTTPBasicAuth(t *testing.T) {
+	s := authServer(t)
+	basic := func(user, password string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.SetBasicAuth(user, password)
+		}
+	}
+
+	_, res, err := postCall(t, s, "d.erase", basic("admin", "secret"))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"admin"}, res)
+
+	_, res, err = postCall(t, s, "d.name", basic("joe", "secret"))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"joe"}, res)
+
+	_, _, err = postCall(t, s, "d.erase", basic("joe", "secret"))
+	assert.Equal(t, NewFault(AccessDenied, "access denied: d.erase"), err)
+
+	rec, _, _ := postCall(t, s, "d.name", basic("joe", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="xmlrpc", charset="UTF-8"`, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestHTTPBearerAuth(t *testing.T) {
+	s := authServer(t)
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	_, res, err := postCall(t, s, "d.erase", bearer("t0ken"))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"admin"}, res)
+
+	rec, _, _ := postCall(t, s, "d.erase", bearer("wrong"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="xmlrpc", error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestAnonymousAccess(t *testing.T) {
+	s := authServer(t)
+
+	_, res, err := postCall(t, s, "system.listMethods", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"system.listMethods"}}, res, "only allowed methods are listed")
+
+	_, _, err = postCall(t, s, "d.name", nil)
+	assert.True(t, IsAccessDenied(err))
+	_, _, err = postCall(t, s, "d.unknown", nil)
+	assert.True(t, IsAccessDenied(err), "existence of methods isn't revealed to denied callers")
+}
+
+func TestACLAppliesToIntrospection(t *testing.T) {
+	s := authServer(t)
+	joe := WithIdentity(context.Background(), users["joe"])
+
+	res, err := s.Call(joe, "system.listMethods", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, res, "d.name")
+	assert.NotContains(t, res, "d.erase")
+
+	_, err = s.Call(joe, "system.methodSignature", []interface{}{"d.name"})
+	assert.Nil(t, err)
+	_, err = s.Call(joe, "system.methodSignature", []interface{}{"d.erase"})
+	assert.Equal(t, NewFault(AccessDenied, "access denied: d.erase"), err)
+	_, err = s.Call(joe, "system.methodHelp", []interface{}{"d.unknown"})
+	assert.True(t, IsAccessDenied(err))
+}
+
+func TestACLAppliesToMulticall(t *testing.T) {
+	s := authServer(t)
+	calls := []interface{}{
+		map[string]interface{}{"methodName": "d.name", "params": []interface{}{}},
+		map[string]interface{}{"methodName": "d.erase", "params": []interface{}{}},
+	}
+
+	res, err := s.Call(WithIdentity(context.Background(), users["joe"]), "system.multicall", []interface{}{calls})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{"joe"},
+		map[string]interface{}{"faultCode": AccessDenied, "faultString": "access denied: d.erase"},
+	}, res)
+}
+
+func TestSCGIAuth(t *testing.T) {
+	l := serveSCGI(t, authServer(t), "tcp", "127.0.0.1:0")
+	defer l.Close()
+	raw := func(name, value string) string {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		body := `<?xml version="1.0"?><methodCall><methodName>d.name</methodName></methodCall>`
+		header := &bytes.Buffer{}
+		writeSCGIHeader(header, "CONTENT_LENGTH", strconv.Itoa(len(body)))
+		writeSCGIHeader(header, "SCGI", "1")
+		writeSCGIHeader(header, name, value)
+		io.WriteString(conn, strconv.Itoa(header.Len())+":"+header.String()+","+body)
+		resp, _ := ioutil.ReadAll(conn)
+		return string(resp)
+	}
+	send := func(name, value string) string {
+		resp, err := readSCGIResponse(bufio.NewReader(strings.NewReader(raw(name, value))))
+		if err != nil {
+			return err.Error()
+		}
+		res, err := NewDecoder(resp).DecodeResponse()
+		if err != nil {
+			return err.Error()
+		}
+		return res[0].(string)
+	}
+
+	assert.Equal(t, "joe", send("REMOTE_USER", "joe"))
+	assert.Equal(t, "unexpected response status: 401 Unauthorized", send("REMOTE_USER", "bob"))
+	assert.Equal(t, "admin", send("HTTP_AUTHORIZATION", "Bearer t0ken"))
+	assert.Contains(t, raw("HTTP_AUTHORIZATION", "Bearer wrong"),
+		"Status: 401 Unauthorized\r\nWWW-Authenticate: Bearer realm=\"xmlrpc\", error=\"invalid_token\"\r\n")
+	assert.Contains(t, raw("REMOTE_USER", "bob"), "WWW-Authenticate: Basic realm=\"xmlrpc\"")
+}
+
+func TestACL(t *testing.T) {
+	acl := ACL{
+		{Identities: []string{"joe"}, Methods: []string{"d.*"}},
+		{Identities: []string{"*"}, Methods: []string{"system.listMethods"}},
+	}
+
+	assert.True(t, acl.allows(&Identity{Name: "joe"}, "d.name"))
+	assert.False(t, acl.allows(&Identity{Name: "joe"}, "t.name"))
+	assert.True(t, acl.allows(&Identity{Name: "bob"}, "system.listMethods"))
+	assert.False(t, acl.allows(&Identity{Name: "bob"}, "d.name"))
+	assert.False(t, acl.allows(nil, "system.listMethods"))
+}
@@ -0,0 +1,36 @@
+package xmlrpc
+
+import (
+	"context"
+)
+
+// Capability describes extension of XML-RPC supported by server, see
+// http://groups.yahoo.com/group/xml-rpc/message/2897
+type Capability struct {
+	SpecURL     string `xmlrpc:"specUrl"`
+	SpecVersion int    `xmlrpc:"specVersion"`
+}
+
+// serverCapabilities are capabilities of Server returned by system.getCapabilities.
+var serverCapabilities = map[string]Capability{
+	"xmlrpc":           {"http://www.xmlrpc.com/spec", 1},
+	"faults_interop":   {"http://xmlrpc-epi.sourceforge.net/specs/rfc.fault_codes.php", 20010516},
+	"introspection":    {"http://xmlrpc-c.sourceforge.net/introspection.html", 1},
+	"system.multicall": {"http://www.xmlrpc.com/discuss/msgReader$1208", 1},
+	"nil":              {"http://ontosys.com/xml-rpc/extensions.php", 20010623},
+}
+
+func (s *Server) registerCapabilities() {
+	s.methods["system.getCapabilities"] = &method{
+		h:          s.getCapabilities,
+		signatures: [][]string{{"struct"}},
+		help:       "Returns struct which describes extensions of XML-RPC supported by the server.",
+	}
+}
+
+func (s *Server) getCapabilities(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) != 0 {
+		return nil, NewFault(InvalidParams, "system.getCapabilities expects no params")
+	}
+	return serverCapabilities, nil
+}
@@ -0,0 +1,119 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	call := `<?xml version="1.0"?><methodCall><methodName>system.getCapabilities</methodName></methodCall>`
+
+	res, err := serve(t, NewServer(), call)
+
+	assert.Nil(t, err)
+	caps := res[0].(map[string]interface{})
+	assert.Equal(t, 5, len(caps))
+	assert.Equal(t, map[string]interface{}{
+		"specUrl":     "http://xmlrpc-epi.sourceforge.net/specs/rfc.fault_codes.php",
+		"specVersion": 20010516,
+	}, caps["faults_interop"])
+	for _, name := range []string{"xmlrpc", "introspection", "system.multicall", "nil"} {
+		assert.Contains(t, caps, name)
+	}
+}
+
+func TestServerSendsNil(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.Register("d.ptr", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		var size *int
+		return size, nil
+	}))
+	assert.Nil(t, s.Register("d.nil", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return nil, nil
+	}))
+
+	for _, method := range []string{"d.ptr", "d.nil"} {
+		buf := &bytes.Buffer{}
+		call := `<?xml version="1.0"?><methodCall><methodName>` + method + `</methodName></methodCall>`
+		assert.Nil(t, s.ServeCall(context.Background(), strings.NewReader(call), buf))
+		assert.Contains(t, buf.String(), "<value><nil/></value>", method)
+		res, err := NewDecoder(buf).DecodeResponse()
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{nil}, res)
+	}
+}
+
+// capabilitiesServer returns server which counts calls of system.getCapabilities and records params of d.set.
+// If fault isn't zero system.getCapabilities fails with fault with this code.
+func capabilitiesServer(t *testing.T, fault int) (l net.Listener, probes *int32, received chan []interface{}) {
+	probes = new(int32)
+	received = make(chan []interface{}, 1)
+	set := func(ctx context.Context, params []interface{}) (interface{}, error) {
+		received <- params
+		return 0, nil
+	}
+	s := newTestServer(t, map[string]Handler{"d.set": set})
+	s.Use(func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error) {
+		if method == "system.getCapabilities" {
+			atomic.AddInt32(probes, 1)
+			if fault != 0 {
+				return nil, NewFault(fault, "method not found: system.getCapabilities")
+			}
+		}
+		return next(ctx, params)
+	})
+	l = serveSCGI(t, s, "tcp", "127.0.0.1:0")
+	return
+}
+
+func TestClientCapabilitiesAreCached(t *testing.T) {
+	l, probes, _ := capabilitiesServer(t, 0)
+	defer l.Close()
+	c := &SCGIXmlRpc{Addr: l.Addr().String()}
+
+	caps, err := c.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, Capability{"http://www.xmlrpc.com/spec", 1}, caps["xmlrpc"])
+	_, err = c.Capabilities()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(probes))
+}
+
+func TestClientSendsNilIfSupported(t *testing.T) {
+	l, probes, received := capabilitiesServer(t, 0)
+	defer l.Close()
+	c := &SCGIXmlRpc{Addr: l.Addr().String(), Nil: NilIfSupported}
+
+	_, err := c.Send("d.set", "h1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"h1", nil}, <-received)
+	_, err = c.Send("d.set", "h2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"h2", nil}, <-received)
+	assert.Equal(t, int32(1), atomic.LoadInt32(probes))
+}
+
+func TestClientDoesNotSendNilIfNotSupported(t *testing.T) {
+	// -506 is code of fault for unknown method of xmlrpc-c, 1 of Python's SimpleXMLRPCServer
+	for _, fault := range []int{MethodNotFound, -506, 1} {
+		l, probes, received := capabilitiesServer(t, fault)
+		c := &SCGIXmlRpc{Addr: l.Addr().String(), Nil: NilIfSupported}
+
+		caps, err := c.Capabilities()
+		assert.Nil(t, err)
+		assert.Empty(t, caps)
+		for _, hash := range []string{"h1", "h2"} {
+			_, err = c.Send("d.set", hash, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{hash, ""}, <-received)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(probes), "fault %d", fault)
+		l.Close()
+	}
+}
@@ -0,0 +1,179 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// charset is single byte character set.
+type charset struct {
+	name   string
+	decode [256]rune
+	encode map[rune]byte
+}
+
+func newCharset(name string, ascii bool, high map[byte]rune) *charset {
+	cs := &charset{name: name, encode: make(map[rune]byte)}
+	for i := 0; i < 256; i++ {
+		r := rune(i)
+		if h, ok := high[byte(i)]; ok {
+			r = h
+		} else if ascii && i >= 0x80 {
+			r = utf8.RuneError
+		}
+		cs.decode[i] = r
+		if r != utf8.RuneError {
+			cs.encode[r] = byte(i)
+		}
+	}
+	return cs
+}
+
+var (
+	usASCII = newCharset("US-ASCII", true, nil)
+	latin1  = newCharset("ISO-8859-1", false, nil)
+	latin9  = newCharset("ISO-8859-15", false, map[byte]rune{
+		0xA4: '€', 0xA6: 'Š', 0xA8: 'š', 0xB4: 'Ž',
+		0xB8: 'ž', 0xBC: 'Œ', 0xBD: 'œ', 0xBE: 'Ÿ',
+	})
+	windows1252 = newCharset("windows-1252", false, map[byte]rune{
+		0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†',
+		0x87: '‡', 0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ',
+		0x8E: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•',
+		0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›',
+		0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+	})
+)
+
+var charsets = map[string]*charset{
+	"us-ascii":     usASCII,
+	"ascii":        usASCII,
+	"iso-8859-1":   latin1,
+	"iso8859-1":    latin1,
+	"iso_8859-1":   latin1,
+	"latin1":       latin1,
+	"l1":           latin1,
+	"cp819":        latin1,
+	"iso-8859-15":  latin9,
+	"iso8859-15":   latin9,
+	"iso_8859-15":  latin9,
+	"latin-9":      latin9,
+	"latin9":       latin9,
+	"windows-1252": windows1252,
+	"cp1252":       windows1252,
+	"x-cp1252":     windows1252,
+}
+
+// lookupCharset returns charset by its name, nil charset means UTF-8.
+func lookupCharset(name string) (*charset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "utf-8" || name == "utf8" || name == "" {
+		return nil, nil
+	}
+	if cs, ok := charsets[name]; ok {
+		return cs, nil
+	}
+	return nil, &unsupportedCharset{name}
+}
+
+type unsupportedCharset struct {
+	name string
+}
+
+func (e *unsupportedCharset) Error() string {
+	return fmt.Sprintf("unsupported charset: %s", e.name)
+}
+
+// charsetReader is used as xml.Decoder.CharsetReader.
+func charsetReader(name string, input io.Reader) (io.Reader, error) {
+	cs, err := lookupCharset(name)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return input, nil
+	}
+	br, ok := input.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(input)
+	}
+	return &charsetDecoder{r: br, cs: cs}, nil
+}
+
+// charsetDecoder converts input to UTF-8. It reads source byte by byte and implements io.ByteReader,
+// so xml.Decoder doesn't read ahead of the current position.
+type charsetDecoder struct {
+	r       io.ByteReader
+	cs      *charset
+	buf     [utf8.UTFMax]byte
+	pending []byte
+}
+
+func (c *charsetDecoder) ReadByte() (b byte, err error) {
+	if len(c.pending) == 0 {
+		if b, err = c.r.ReadByte(); err != nil {
+			return
+		}
+		n := utf8.EncodeRune(c.buf[:], c.cs.decode[b])
+		c.pending = c.buf[:n]
+	}
+	b = c.pending[0]
+	c.pending = c.pending[1:]
+	return
+}
+
+func (c *charsetDecoder) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		var b byte
+		if b, err = c.ReadByte(); err != nil {
+			if n > 0 {
+				err = nil
+			}
+			return
+		}
+		p[n] = b
+		n++
+	}
+	return
+}
+
+// charsetEncoder converts UTF-8 to charset, characters that don't fit the charset are written as
+// character references, e.g. &#8364; It's valid as long as such characters appear only in text content.
+type charsetEncoder struct {
+	w       io.Writer
+	cs      *charset
+	pending []byte
+}
+
+func (c *charsetEncoder) Write(p []byte) (n int, err error) {
+	data := p
+	if len(c.pending) > 0 {
+		data = append(c.pending, p...)
+		c.pending = nil
+	}
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			c.pending = append([]byte(nil), data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if b, ok := c.cs.encode[r]; ok && r != utf8.RuneError {
+			out = append(out, b)
+		} else {
+			out = append(out, "&#"...)
+			out = strconv.AppendInt(out, int64(r), 10)
+			out = append(out, ';')
+		}
+	}
+	if _, err = c.w.Write(out); err != nil {
+		return
+	}
+	n = len(p)
+	return
+}
@@ -0,0 +1,77 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeLatin1Response(t *testing.T) {
+	xml := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<methodResponse><params><param><value><string>caf\xe9</string></value></param></params></methodResponse>"
+
+	res, err := NewDecoder(strings.NewReader(xml)).DecodeResponse()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"café"}, res)
+}
+
+func TestDecodeWindows1252Response(t *testing.T) {
+	xml := "<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n" +
+		"<methodResponse><params><param><value><struct>" +
+		"<member><name>pri\xe7e</name><value><string>\x8010 \x96 it\x92s cheap</string></value></member>" +
+		"</struct></value></param></params></methodResponse>"
+
+	res, err := NewDecoder(strings.NewReader(xml)).DecodeResponse()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"priçe": "€10 – it’s cheap"}}, res)
+}
+
+func TestDecodeUnsupportedCharset(t *testing.T) {
+	xml := "<?xml version=\"1.0\" encoding=\"KOI8-R\"?>\n" +
+		"<methodResponse><params><param><value><string>hello</string></value></param></params></methodResponse>"
+
+	_, err := NewDecoder(strings.NewReader(xml)).DecodeResponse()
+
+	assert.Contains(t, causeOf(err).Error(), "unsupported charset: koi8-r")
+}
+
+func TestEncodeCallInLatin1(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	assert.Nil(t, enc.SetCharset("ISO-8859-1"))
+
+	err := enc.EncodeCall("message", "café €5")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>"+
+		"<methodCall><methodName>message</methodName><params>"+
+		"<param><value><string>caf\xe9 &#8364;5</string></value></param>"+
+		"</params></methodCall>", buf.String())
+
+	_, params, err := NewDecoder(buf).DecodeCall()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"café €5"}, params)
+}
+
+func TestEncodeUnsupportedCharset(t *testing.T) {
+	err := NewEncoder(&bytes.Buffer{}).SetCharset("KOI8-R")
+
+	assert.Equal(t, "unsupported charset: koi8-r", err.Error())
+}
+
+func TestCharsetEncoderKeepsSplitRune(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := &charsetEncoder{w: buf, cs: windows1252}
+	euro := []byte("€")
+
+	w.Write([]byte("a"))
+	w.Write(euro[:1])
+	w.Write(euro[1:])
+	w.Write([]byte("ü✓"))
+
+	assert.Equal(t, "a\x80\xfc&#10003;", buf.String())
+}
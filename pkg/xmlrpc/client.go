@@ -1,8 +1,14 @@
 package xmlrpc
 
 import (
+	"bufio"
 	"bytes"
-	"github.com/mpl/scgiclient"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
 )
 
 type Client interface {
@@ -11,22 +17,173 @@ type Client interface {
 
 type SCGIXmlRpc struct {
 	Addr string
-	marshaller
-	unmarshaller
+	// Limits restricts responses accepted by client.
+	Limits Limits
+	// Mode defines how strictly client follows the XML-RPC specification when reads responses.
+	Mode Mode
+	// Charset of requests, default is UTF-8, see Encoder.SetCharset.
+	Charset string
+	// InvalidChars defines how strings that contain characters not allowed in XML are sent,
+	// see Encoder.SetInvalidCharPolicy.
+	InvalidChars InvalidCharPolicy
+	// Nil defines whether nil arguments are sent as <nil/>, see Encoder.SetNil.
+	Nil NilPolicy
+
+	capsMu sync.Mutex
+	caps   map[string]Capability
 }
 
+// NilPolicy defines whether client sends nil values as <nil/>.
+type NilPolicy int
+
+const (
+	// NilDisabled sends nil as empty value, it's default.
+	NilDisabled NilPolicy = iota
+	// NilEnabled sends nil as <nil/>.
+	NilEnabled
+	// NilIfSupported sends nil as <nil/> if server advertises capability "nil", see SCGIXmlRpc.Capabilities.
+	NilIfSupported
+)
+
 func CreateSCGIClient(addr string) Client {
 	return &SCGIXmlRpc{Addr: addr}
 }
 
 func (s *SCGIXmlRpc) Send(method string, args ...interface{}) (params []interface{}, err error) {
-	body, err := s.marshal(method, args...)
+	conn, resp, err := s.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return s.decoder(resp).DecodeResponse()
+}
+
+// SendInto sends method call and decodes response params into results, see Decoder.DecodeResponseInto.
+func (s *SCGIXmlRpc) SendInto(results []interface{}, method string, args ...interface{}) error {
+	conn, resp, err := s.call(method, args...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return s.decoder(resp).DecodeResponseInto(results...)
+}
+
+// SendArray sends method call and returns iterator over elements of the array found at path in response,
+// see Decoder.DecodeResponseArray. The iterator must be closed to release connection.
+func (s *SCGIXmlRpc) SendArray(path []interface{}, method string, args ...interface{}) (*ArrayIterator, error) {
+	conn, resp, err := s.call(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	it, err := s.decoder(resp).DecodeResponseArray(path...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	it.closer = conn
+	return it, nil
+}
+
+// Capabilities returns capabilities advertised by server with system.getCapabilities. They are requested once
+// and cached. Server which answers system.getCapabilities with fault, e.g. because it doesn't support the method,
+// has no capabilities.
+func (s *SCGIXmlRpc) Capabilities() (map[string]Capability, error) {
+	s.capsMu.Lock()
+	defer s.capsMu.Unlock()
+	if s.caps != nil {
+		return s.caps, nil
+	}
+	conn, resp, err := s.send(false, "system.getCapabilities")
 	if err != nil {
 		return nil, err
 	}
-	resp, err := scgiclient.Send(s.Addr, bytes.NewReader(body))
+	defer conn.Close()
+	caps := make(map[string]Capability)
+	if err = s.decoder(resp).DecodeResponseInto(&caps); err != nil {
+		// code of fault for unknown method isn't the same in all servers, e.g. -506 in xmlrpc-c
+		if _, ok := err.(*Fault); !ok {
+			return nil, err
+		}
+	}
+	s.caps = caps
+	return caps, nil
+}
+
+func (s *SCGIXmlRpc) decoder(resp io.Reader) *Decoder {
+	dec := NewDecoder(resp)
+	dec.SetLimits(s.Limits)
+	dec.SetMode(s.Mode)
+	return dec
+}
+
+func (s *SCGIXmlRpc) call(method string, args ...interface{}) (conn net.Conn, resp io.Reader, err error) {
+	nilValues := s.Nil == NilEnabled
+	if s.Nil == NilIfSupported {
+		var caps map[string]Capability
+		if caps, err = s.Capabilities(); err != nil {
+			return
+		}
+		_, nilValues = caps["nil"]
+	}
+	return s.send(nilValues, method, args...)
+}
+
+func (s *SCGIXmlRpc) send(nilValues bool, method string, args ...interface{}) (conn net.Conn, resp io.Reader, err error) {
+	// length of request is computed without reading of streams, so the request is sent without loading it
+	// into memory, see Base64Stream
+	size := &limitedWriter{w: ioutil.Discard}
+	var enc *Encoder
+	if enc, err = s.encoder(size, nilValues); err != nil {
+		return
+	}
+	enc.m.sizeOnly = true
+	var body *bytes.Buffer
+	if err = enc.EncodeCall(method, args...); err == errUnknownSize {
+		body = &bytes.Buffer{}
+		enc, _ = s.encoder(body, nilValues)
+		err = enc.EncodeCall(method, args...)
+	}
+	if err != nil {
+		return
+	}
+	if conn, err = dialSCGI(s.Addr); err != nil {
+		return
+	}
+	if body != nil {
+		err = writeSCGIRequest(conn, body.Bytes())
+	} else {
+		err = s.stream(conn, size.n, nilValues, method, args...)
+	}
+	if err == nil {
+		resp, err = readSCGIResponse(bufio.NewReader(conn))
+	}
 	if err != nil {
+		conn.Close()
+		conn = nil
+	}
+	return
+}
+
+// stream encodes request which has the given length directly into conn.
+func (s *SCGIXmlRpc) stream(conn net.Conn, length int64, nilValues bool, method string, args ...interface{}) error {
+	if err := writeSCGIRequestHeader(conn, length); err != nil {
+		return err
+	}
+	body := &limitedWriter{w: conn, max: length, limit: "CONTENT_LENGTH"}
+	enc, _ := s.encoder(body, nilValues)
+	err := enc.EncodeCall(method, args...)
+	if _, exceeded := err.(*LimitExceeded); exceeded || err == nil && body.n != length {
+		return errors.New(fmt.Sprintf("size of request isn't %d bytes, content of stream changed", length))
+	}
+	return err
+}
+
+func (s *SCGIXmlRpc) encoder(w io.Writer, nilValues bool) (*Encoder, error) {
+	enc := NewEncoder(w)
+	if err := enc.SetCharset(s.Charset); err != nil {
 		return nil, err
 	}
-	return s.unmarshal(resp.Body)
+	enc.SetInvalidCharPolicy(s.InvalidChars)
+	enc.SetNil(nilValues)
+	return enc, nil
 }
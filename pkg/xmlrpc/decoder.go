@@ -0,0 +1,134 @@
+package xmlrpc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Decoder reads XML-RPC documents from an input stream. Besides UTF-8 it reads documents encoded
+// in US-ASCII, ISO-8859-1, ISO-8859-15 and windows-1252.
+type Decoder struct {
+	d  *xml.Decoder
+	in *input
+	u  unmarshaller
+	// charsetErr is set if document declares unsupported charset, xml.Decoder doesn't keep the original error
+	charsetErr error
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	in := newInput(r)
+	dec := &Decoder{d: xml.NewDecoder(in), in: in}
+	dec.d.CharsetReader = func(name string, input io.Reader) (cr io.Reader, err error) {
+		if cr, err = charsetReader(name, input); err != nil {
+			dec.charsetErr = err
+		}
+		return
+	}
+	return dec
+}
+
+// SetLimits restricts documents read by the decoder, exceeded limit is reported with *LimitExceeded error.
+func (dec *Decoder) SetLimits(l Limits) {
+	dec.u.limits = l
+	dec.in.max = l.MaxBodyBytes
+}
+
+// SetMode sets how strictly the decoder follows the XML-RPC specification, default is Lenient.
+func (dec *Decoder) SetMode(m Mode) {
+	dec.u.mode = m
+}
+
+// DecodeError describes failure of decoding XML-RPC document, Err is the cause of failure.
+type DecodeError struct {
+	Line   int
+	Column int
+	// Path is logical path of the value being decoded, e.g. params[0][1532].struct["d.name"]
+	Path string
+	// Excerpt is the input read just before the failure.
+	Excerpt string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	at := fmt.Sprintf("line %d, column %d", e.Line, e.Column)
+	if e.Path != "" {
+		at += ", " + e.Path
+	}
+	return fmt.Sprintf("decode error at %s: %v, near %q", at, e.Err, e.Excerpt)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeCall reads <methodCall> document and returns method name and its params.
+func (dec *Decoder) DecodeCall() (method string, params []interface{}, err error) {
+	method, params, err = dec.u.unmarshalCall(dec.d)
+	err = dec.wrap(err)
+	return
+}
+
+// DecodeCallInto reads <methodCall> document, returns method name and decodes its params into targets
+// the same way as DecodeResponseInto does.
+func (dec *Decoder) DecodeCallInto(targets ...interface{}) (method string, err error) {
+	var tv []reflect.Value
+	if tv, err = targetsOf(targets); err != nil {
+		return
+	}
+	method, err = dec.u.unmarshalCallTo(dec.d, paramTargets(tv))
+	err = dec.wrap(err)
+	return
+}
+
+// DecodeResponse reads <methodResponse> document and returns its params.
+// If response contains <fault> the returned error is *Fault.
+func (dec *Decoder) DecodeResponse() (params []interface{}, err error) {
+	params, err = dec.u.unmarshalResponse(dec.d)
+	err = dec.wrap(err)
+	return
+}
+
+// DecodeResponseInto reads <methodResponse> document and decodes its params into targets, each target must be
+// a non-nil pointer. Params that have no target are skipped. Content of <base64> decoded into io.Writer,
+// e.g. *os.File, is written to it as stream instead of being held in memory.
+// If response contains <fault> the returned error is *Fault.
+func (dec *Decoder) DecodeResponseInto(targets ...interface{}) error {
+	tv, err := targetsOf(targets)
+	if err != nil {
+		return err
+	}
+	return dec.wrap(dec.u.unmarshalResponseTo(dec.d, paramTargets(tv)))
+}
+
+// DecodeValue reads next <value> element. It returns io.EOF if there are no more values.
+func (dec *Decoder) DecodeValue() (v interface{}, err error) {
+	var ok bool
+	if ok, err = dec.u.unmarshalValueTo(dec.d, reflect.ValueOf(&v).Elem()); err == nil && !ok {
+		err = io.EOF
+	}
+	err = dec.wrap(err)
+	return
+}
+
+// wrap adds position of decoder to err, faults and io.EOF are returned as is.
+func (dec *Decoder) wrap(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*Fault); ok {
+		return err
+	}
+	if dec.charsetErr != nil {
+		err = dec.charsetErr
+	}
+	return &DecodeError{
+		Line:    dec.in.line,
+		Column:  dec.in.column,
+		Path:    strings.Join(dec.u.path, ""),
+		Excerpt: dec.in.excerpt(),
+		Err:     err,
+	}
+}
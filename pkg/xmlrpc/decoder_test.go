@@ -0,0 +1,281 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeCall(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodCall>
+    <methodName>d.multicall2</methodName>
+    <params>
+        <param><value><string></string></value></param>
+        <param><value><string>main</string></value></param>
+        <param><value><string>d.name=</string></value></param>
+    </params>
+</methodCall>
+`
+
+	method, params, err := NewDecoder(strings.NewReader(xml)).DecodeCall()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "d.multicall2", method)
+	assert.Equal(t, []interface{}{"", "main", "d.name="}, params)
+}
+
+func TestDecodeCallWithoutParams(t *testing.T) {
+	xml := `<?xml version="1.0"?><methodCall><methodName>system.listMethods</methodName></methodCall>`
+
+	method, params, err := NewDecoder(strings.NewReader(xml)).DecodeCall()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "system.listMethods", method)
+	assert.Nil(t, params)
+}
+
+func TestDecodeCallFailsOnResponse(t *testing.T) {
+	xml := `<?xml version="1.0"?><methodResponse><params></params></methodResponse>`
+
+	_, _, err := NewDecoder(strings.NewReader(xml)).DecodeCall()
+
+	assert.NotNil(t, err)
+}
+
+func TestDecodeCallValidatesMethodName(t *testing.T) {
+	doc := `<?xml version="1.0"?><methodCall><methodName>a&lt;b</methodName><params></params></methodCall>`
+
+	_, _, err := NewDecoder(strings.NewReader(doc)).DecodeCall()
+
+	assert.Equal(t, `invalid method name "a<b", character '<' is not allowed`, causeOf(err).Error())
+}
+
+func TestDecodeCallInto(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<methodCall>
+    <methodName>d.multicall2</methodName>
+    <params>
+        <param><value><string></string></value></param>
+        <param><value><string>main</string></value></param>
+        <param><value><array><data>
+            <value><string>d.name=</string></value>
+            <value><string>d.size_bytes=</string></value>
+        </data></array></value></param>
+    </params>
+</methodCall>
+`
+	var target, view string
+	var commands []string
+
+	method, err := NewDecoder(strings.NewReader(doc)).DecodeCallInto(&target, &view, &commands)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "d.multicall2", method)
+	assert.Equal(t, "", target)
+	assert.Equal(t, "main", view)
+	assert.Equal(t, []string{"d.name=", "d.size_bytes="}, commands)
+}
+
+func TestDecodeCallIntoReportsTypeMismatch(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<methodCall><methodName>d.open</methodName><params><param><value><i4>1</i4></value></param></params></methodCall>`
+	var hash string
+
+	_, err := NewDecoder(strings.NewReader(doc)).DecodeCallInto(&hash)
+
+	assert.Equal(t, "params[0]", err.(*DecodeError).Path)
+	assert.Equal(t, "cannot decode int into string", causeOf(err).Error())
+}
+
+func TestDecodeResponse(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><i8>123</i8></value></param>
+    </params>
+</methodResponse>
+`
+
+	params, err := NewDecoder(strings.NewReader(xml)).DecodeResponse()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(123)}, params)
+}
+
+func TestDecodeResponseInto(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><struct>
+            <member><name>name</name><value><string>ubuntu.iso</string></value></member>
+            <member><name>size</name><value><i8>2097152</i8></value></member>
+            <member><name>complete</name><value><boolean>1</boolean></value></member>
+        </struct></value></param>
+        <param><value><i4>1</i4></value></param>
+    </params>
+</methodResponse>
+`
+	var torrent struct {
+		Name     string
+		Size     int64
+		Complete bool
+	}
+
+	err := NewDecoder(strings.NewReader(xml)).DecodeResponseInto(&torrent)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ubuntu.iso", torrent.Name)
+	assert.Equal(t, int64(2097152), torrent.Size)
+	assert.True(t, torrent.Complete)
+}
+
+func TestDecodeResponseIntoStreamsBase64ToWriter(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><base64>
+            dG8gYmUg
+            b3Igbm90IHRvIGJl
+        </base64></value></param>
+    </params>
+</methodResponse>
+`
+	buf := &bytes.Buffer{}
+
+	err := NewDecoder(strings.NewReader(xml)).DecodeResponseInto(buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "to be or not to be", buf.String())
+}
+
+func TestDecodeResponseIntoStreamsBase64ToStructField(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><struct>
+            <member><name>name</name><value><string>hello.txt</string></value></member>
+            <member><name>data</name><value><base64>aGVsbG8=</base64></value></member>
+        </struct></value></param>
+    </params>
+</methodResponse>
+`
+	var file struct {
+		Name string
+		Data bytes.Buffer
+	}
+
+	err := NewDecoder(strings.NewReader(xml)).DecodeResponseInto(&file)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hello.txt", file.Name)
+	assert.Equal(t, "hello", file.Data.String())
+}
+
+func TestDecodeResponseIntoRequiresPointer(t *testing.T) {
+	var s string
+
+	err := NewDecoder(strings.NewReader("")).DecodeResponseInto(s)
+
+	assert.Equal(t, "decode target must be a non-nil pointer, got string", err.Error())
+}
+
+func TestDecodeValues(t *testing.T) {
+	xml := `<value><i4>1</i4></value><value><array><data><value><string>a</string></value></data></array></value>`
+	dec := NewDecoder(strings.NewReader(xml))
+
+	v, err := dec.DecodeValue()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	v, err = dec.DecodeValue()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a"}, v)
+
+	_, err = dec.DecodeValue()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecodeNil(t *testing.T) {
+	doc := `<?xml version="1.0"?><methodResponse><params><param><value><struct>
+<member><name>name</name><value><nil/></value></member>
+<member><name>size</name><value><ex:nil xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"/></value></member>
+</struct></value></param></params></methodResponse>`
+	name := "ubuntu.iso"
+	torrent := struct {
+		Name *string
+		Size int64
+	}{&name, 1}
+
+	err := NewDecoder(strings.NewReader(doc)).DecodeResponseInto(&torrent)
+
+	assert.Nil(t, err)
+	assert.Nil(t, torrent.Name)
+	assert.Equal(t, int64(0), torrent.Size)
+}
+
+func TestDecodeValueReturnsNil(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`<value><nil/></value><value><i4>1</i4></value>`))
+
+	v, err := dec.DecodeValue()
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+	v, err = dec.DecodeValue()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	_, err = dec.DecodeValue()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecodeErrorReportsPosition(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><array><data>
+            <value><array><data>
+                <value><string>ubuntu.iso</string></value>
+            </data></array></value>
+            <value><struct>
+                <member><name>d.name</name><value><double>1.5</double></value></member>
+            </struct></value>
+        </data></array></value></param>
+    </params>
+</methodResponse>
+`
+
+	_, err := NewDecoder(strings.NewReader(xml)).DecodeResponse()
+
+	de, ok := err.(*DecodeError)
+	assert.True(t, ok)
+	assert.Equal(t, 9, de.Line)
+	assert.Equal(t, 58, de.Column)
+	assert.Equal(t, `params[0][1].struct["d.name"]`, de.Path)
+	assert.True(t, strings.HasSuffix(de.Excerpt, "<name>d.name</name><value><double>"))
+	assert.Equal(t, "unsupported type: double", de.Err.Error())
+	assert.Equal(t, `decode error at line 9, column 58, params[0][1].struct["d.name"]: unsupported type: double, near "`+de.Excerpt+`"`, de.Error())
+}
+
+func TestDecodeErrorReportsSyntaxError(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<methodResponse><params><param><value><i4>1</i4></value>`
+
+	_, err := NewDecoder(strings.NewReader(doc)).DecodeResponse()
+
+	de, ok := err.(*DecodeError)
+	assert.True(t, ok)
+	assert.Equal(t, 2, de.Line)
+	assert.Equal(t, "params", de.Path)
+	_, isSyntaxError := de.Err.(*xml.SyntaxError)
+	assert.True(t, isSyntaxError)
+}
+
+func causeOf(err error) error {
+	if de, ok := err.(*DecodeError); ok {
+		return de.Err
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"io"
+)
+
+// Encoder writes XML-RPC documents to an output stream.
+type Encoder struct {
+	w *bufio.Writer
+	m marshaller
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// SetCharset sets encoding of produced documents, default is UTF-8. Supported charsets are UTF-8, US-ASCII,
+// ISO-8859-1, ISO-8859-15 and windows-1252. Characters that don't fit the charset are written as character
+// references.
+func (e *Encoder) SetCharset(name string) error {
+	cs, err := lookupCharset(name)
+	if err != nil {
+		return err
+	}
+	e.m.charset = cs
+	return nil
+}
+
+// SetInvalidCharPolicy sets how strings that contain characters not allowed in XML are encoded,
+// default is ReplaceInvalidChars.
+func (e *Encoder) SetInvalidCharPolicy(p InvalidCharPolicy) {
+	e.m.invalidChars = p
+}
+
+// SetNil enables writing nil values, including nil pointers, as <nil/> which is an extension of XML-RPC,
+// see http://ontosys.com/xml-rpc/extensions.php. By default nil is written as empty value and nil pointer
+// can't be written.
+func (e *Encoder) SetNil(enabled bool) {
+	e.m.nilValues = enabled
+}
+
+// EncodeCall writes <methodCall> document with the given method name and arguments.
+func (e *Encoder) EncodeCall(method string, args ...interface{}) error {
+	if err := e.m.marshalCall(e.w, method, args...); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// EncodeResponse writes successful <methodResponse> document with the given params.
+func (e *Encoder) EncodeResponse(params ...interface{}) error {
+	if err := e.m.marshalResponse(e.w, params...); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// EncodeFault writes <methodResponse> document with <fault>.
+func (e *Encoder) EncodeFault(f *Fault) error {
+	if err := e.m.marshalFault(e.w, f); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
@@ -0,0 +1,171 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeCall(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf).EncodeCall("message", "hello", 123)
+	assert.Nil(t, err)
+	expected := formatXml(`<?xml version="1.0" encoding="UTF-8"?>
+<methodCall>
+    <methodName>message</methodName>
+    <params>
+        <param><value><string>hello</string></value></param>
+        <param><value><i4>123</i4></value></param>
+    </params>
+</methodCall>`)
+	assert.Equal(t, expected, formatXml(buf.String()))
+}
+
+func TestEncodeResponse(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf).EncodeResponse([]interface{}{"hello", true})
+	assert.Nil(t, err)
+	expected := formatXml(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><array><data>
+            <value><string>hello</string></value>
+            <value><boolean>1</boolean></value>
+        </data></array></value></param>
+    </params>
+</methodResponse>`)
+	assert.Equal(t, expected, formatXml(buf.String()))
+}
+
+func TestEncodeFault(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf).EncodeFault(NewFault(MethodNotFound, "no such method"))
+	assert.Nil(t, err)
+
+	res, err := NewDecoder(buf).DecodeResponse()
+	assert.Nil(t, res)
+	assert.Equal(t, NewFault(MethodNotFound, "no such method"), err)
+}
+
+func TestEncodeReplacesInvalidChars(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf).EncodeResponse("a\x00b\xffc", map[string]interface{}{"n\x01": 1})
+	assert.Nil(t, err)
+
+	res, err := NewDecoder(buf).DecodeResponse()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a�b�c", map[string]interface{}{"n�": 1}}, res)
+}
+
+func TestEncodeRejectsInvalidChars(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+	enc.SetInvalidCharPolicy(RejectInvalidChars)
+	assert.Equal(t, `string "a\x00b" contains characters not allowed in XML`, enc.EncodeResponse("a\x00b").Error())
+	assert.Equal(t,
+		`struct member name "n\x01" contains characters not allowed in XML`,
+		enc.EncodeResponse(map[string]interface{}{"n\x01": 1}).Error())
+}
+
+func TestEncodeInvalidCharsAsBase64(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.SetInvalidCharPolicy(Base64InvalidChars)
+	err := enc.EncodeResponse([]interface{}{"valid", "a\x00b\xff"})
+	assert.Nil(t, err)
+
+	res, err := NewDecoder(buf).DecodeResponse()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"valid", []byte("a\x00b\xff")}}, res)
+
+	err = enc.EncodeResponse(struct {
+		Name string `xmlrpc:"n\x01"`
+	}{"x"})
+	assert.Equal(t, `struct member name "n\x01" contains characters not allowed in XML`, err.Error())
+}
+
+func TestEncodeResponseIsSymmetricWithDecodeResponseInto(t *testing.T) {
+	type state string
+	type file struct {
+		Path     string `xmlrpc:"path"`
+		Size     uint32 `xmlrpc:"size"`
+		Priority int8   `xmlrpc:"priority"`
+	}
+	type torrent struct {
+		Name   string            `xmlrpc:"name"`
+		State  state             `xmlrpc:"state"`
+		Bytes  uint64            `xmlrpc:"bytes"`
+		Hash   [2]string         `xmlrpc:"hash"`
+		Files  []file            `xmlrpc:"files"`
+		Labels map[string]string `xmlrpc:"labels"`
+		Data   []byte            `xmlrpc:"data"`
+		Active *bool             `xmlrpc:"active"`
+	}
+	active := true
+	sent := torrent{
+		Name:   "ubuntu.iso",
+		State:  "seeding",
+		Bytes:  1 << 40,
+		Hash:   [2]string{"a", "b"},
+		Files:  []file{{"ubuntu.iso", 1024, -1}},
+		Labels: map[string]string{"os": "linux", "arch": "amd64"},
+		Data:   []byte{0, 1, 2},
+		Active: &active,
+	}
+	buf := &bytes.Buffer{}
+	assert.Nil(t, NewEncoder(buf).EncodeResponse(sent))
+
+	var received torrent
+	err := NewDecoder(buf).DecodeResponseInto(&received)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sent, received)
+}
+
+func TestEncodeResponseWritesLargeIntAsI8(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf).EncodeResponse(int(1)<<40, uint(7))
+	assert.Nil(t, err)
+	expected := formatXml(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><i8>1099511627776</i8></value></param>
+        <param><value><i4>7</i4></value></param>
+    </params>
+</methodResponse>`)
+	assert.Equal(t, expected, formatXml(buf.String()))
+}
+
+func TestEncodeResponseFailsOnUnsupportedValues(t *testing.T) {
+	enc := NewEncoder(&bytes.Buffer{})
+	var nilPtr *int
+	assert.Equal(t, "cannot encode nil *int", enc.EncodeResponse(nilPtr).Error())
+	assert.Equal(t, "value 18446744073709551615 overflows i8", enc.EncodeResponse(uint64(1<<64-1)).Error())
+	assert.Equal(t,
+		"unsupported type: map[int]string, map key must be string",
+		enc.EncodeResponse(map[int]string{1: "a"}).Error())
+	assert.Equal(t, "unsupported type: float64", enc.EncodeResponse(1.5).Error())
+}
+
+func TestEncodeNil(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf)
+	enc.SetNil(true)
+	var ptr *int
+	err := enc.EncodeResponse([]interface{}{nil, ptr})
+	assert.Nil(t, err)
+	expected := formatXml(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><array><data>
+            <value><nil/></value>
+            <value><nil/></value>
+        </data></array></value></param>
+    </params>
+</methodResponse>`)
+	assert.Equal(t, expected, formatXml(buf.String()))
+
+	res, err := NewDecoder(buf).DecodeResponse()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{nil, nil}}, res)
+}
@@ -0,0 +1,87 @@
+package xmlrpc
+
+import (
+	"fmt"
+)
+
+// Fault codes from the specification for fault code interoperability,
+// see http://xmlrpc-epi.sourceforge.net/specs/rfc.fault_codes.php
+const (
+	ParseError          = -32700
+	UnsupportedEncoding = -32701
+	InvalidCharacter    = -32702
+	InvalidXmlRpc       = -32600
+	MethodNotFound      = -32601
+	InvalidParams       = -32602
+	InternalError       = -32603
+	ApplicationError    = -32500
+	SystemError         = -32400
+	TransportError      = -32300
+)
+
+// AccessDenied is fault code of calls denied by Server.ACL. It isn't defined by the specification, so it's
+// outside of codes reserved by the specification, the same as HTTP status Forbidden.
+const AccessDenied = 403
+
+// Fault is an error reported by XML-RPC server with <fault> response.
+type Fault struct {
+	Code int
+	Text string
+}
+
+func NewFault(code int, text string) *Fault {
+	return &Fault{Code: code, Text: text}
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("error response, code: %d, text: %s", f.Code, f.Text)
+}
+
+func IsParseError(err error) bool {
+	return hasFaultCode(err, ParseError)
+}
+
+func IsUnsupportedEncoding(err error) bool {
+	return hasFaultCode(err, UnsupportedEncoding)
+}
+
+func IsInvalidCharacter(err error) bool {
+	return hasFaultCode(err, InvalidCharacter)
+}
+
+func IsInvalidXmlRpc(err error) bool {
+	return hasFaultCode(err, InvalidXmlRpc)
+}
+
+func IsMethodNotFound(err error) bool {
+	return hasFaultCode(err, MethodNotFound)
+}
+
+func IsInvalidParams(err error) bool {
+	return hasFaultCode(err, InvalidParams)
+}
+
+func IsInternalError(err error) bool {
+	return hasFaultCode(err, InternalError)
+}
+
+func IsApplicationError(err error) bool {
+	return hasFaultCode(err, ApplicationError)
+}
+
+func IsSystemError(err error) bool {
+	return hasFaultCode(err, SystemError)
+}
+
+func IsTransportError(err error) bool {
+	return hasFaultCode(err, TransportError)
+}
+
+func IsAccessDenied(err error) bool {
+	return hasFaultCode(err, AccessDenied)
+}
+
+func hasFaultCode(err error, code int) bool {
+	f, ok := err.(*Fault)
+	return ok && f.Code == code
+}
@@ -0,0 +1,82 @@
+package xmlrpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultError(t *testing.T) {
+	f := NewFault(MethodNotFound, "method not found: foo")
+
+	assert.Equal(t, "error response, code: -32601, text: method not found: foo", f.Error())
+}
+
+func TestFaultPredicates(t *testing.T) {
+	assert.True(t, IsParseError(NewFault(ParseError, "")))
+	assert.True(t, IsUnsupportedEncoding(NewFault(UnsupportedEncoding, "")))
+	assert.True(t, IsInvalidCharacter(NewFault(InvalidCharacter, "")))
+	assert.True(t, IsInvalidXmlRpc(NewFault(InvalidXmlRpc, "")))
+	assert.True(t, IsMethodNotFound(NewFault(MethodNotFound, "")))
+	assert.True(t, IsInvalidParams(NewFault(InvalidParams, "")))
+	assert.True(t, IsInternalError(NewFault(InternalError, "")))
+	assert.True(t, IsApplicationError(NewFault(ApplicationError, "")))
+	assert.True(t, IsSystemError(NewFault(SystemError, "")))
+	assert.True(t, IsTransportError(NewFault(TransportError, "")))
+
+	assert.False(t, IsMethodNotFound(NewFault(InvalidParams, "")))
+	assert.False(t, IsMethodNotFound(errors.New("method not found")))
+	assert.False(t, IsMethodNotFound(nil))
+}
+
+func TestUnmarshalInteropFault(t *testing.T) {
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+   <fault>
+      <value>
+         <struct>
+            <member>
+               <name>faultCode</name>
+               <value><int>-32601</int></value>
+            </member>
+            <member>
+               <name>faultString</name>
+               <value><string>server error. requested method not found</string></value>
+            </member>
+         </struct>
+      </value>
+   </fault>
+</methodResponse>
+	`)
+
+	u := unmarshaller{}
+	_, err := u.unmarshal(xml)
+
+	assert.True(t, IsMethodNotFound(err))
+	assert.Equal(t, &Fault{Code: MethodNotFound, Text: "server error. requested method not found"}, err)
+}
+
+func TestUnmarshalFaultWithoutCode(t *testing.T) {
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+   <fault>
+      <value>
+         <struct>
+            <member>
+               <name>faultString</name>
+               <value><string>something went wrong</string></value>
+            </member>
+         </struct>
+      </value>
+   </fault>
+</methodResponse>
+	`)
+
+	u := unmarshaller{}
+	_, err := u.unmarshal(xml)
+
+	assert.NotNil(t, err)
+	_, isFault := err.(*Fault)
+	assert.False(t, isFault)
+}
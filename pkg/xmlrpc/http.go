@@ -0,0 +1,55 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// ServeHTTP serves XML-RPC calls sent with POST requests with content type text/xml. Other methods are rejected
+// with 405, other content types with 415 and bodies larger than Limits.MaxBodyBytes with 413. Failures of
+// calls are sent as faults with status 200. After Shutdown requests are rejected with 503 and requests with
+// invalid credentials with 401, see Server.Authenticators.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.begin() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.end()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "text/xml" {
+		http.Error(w, "content type must be text/xml", http.StatusUnsupportedMediaType)
+		return
+	}
+	max := s.Limits.MaxBodyBytes
+	if max > 0 && r.ContentLength > max {
+		http.Error(w, fmt.Sprintf("body must not be larger than %d bytes", max), http.StatusRequestEntityTooLarge)
+		return
+	}
+	ctx, err := s.authenticate(r.Context(), &Credentials{Header: r.Header})
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", challengeOf(err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	// size of body sent without Content-Length, e.g. chunked, is known only when it's read
+	method, params, err := s.decodeCall(r.Body)
+	if isBodyTooLarge(err) {
+		http.Error(w, fmt.Sprintf("body must not be larger than %d bytes", max), http.StatusRequestEntityTooLarge)
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err = s.respond(ctx, buf, method, params, err); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", s.contentType())
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
@@ -0,0 +1,93 @@
+package xmlrpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTP(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t, torrentMethods))
+	defer ts.Close()
+	call := `<?xml version="1.0"?><methodCall><methodName>d.name</methodName>
+<params><param><value>h1</value></param></params></methodCall>`
+
+	resp, err := http.Post(ts.URL, "text/xml; charset=UTF-8", strings.NewReader(call))
+
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/xml; charset=UTF-8", resp.Header.Get("Content-Type"))
+	res, err := NewDecoder(resp.Body).DecodeResponse()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"ubuntu.iso"}, res)
+}
+
+func TestServeHTTPSendsFault(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/RPC2", newTestServer(t, torrentMethods))
+	call := `<?xml version="1.0"?><methodCall><methodName>d.open</methodName></methodCall>`
+	req := httptest.NewRequest(http.MethodPost, "/RPC2", strings.NewReader(call))
+	req.Header.Set("Content-Type", "text/xml")
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, err := NewDecoder(rec.Body).DecodeResponse()
+	assert.Equal(t, NewFault(ApplicationError, "application error"), err)
+}
+
+func TestServeHTTPRejectsInvalidRequests(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	s.Limits = Limits{MaxBodyBytes: 100}
+	call := `<?xml version="1.0"?><methodCall><methodName>d.name</methodName></methodCall>`
+
+	tests := []struct {
+		method      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{http.MethodGet, "text/xml", "", http.StatusMethodNotAllowed},
+		{http.MethodPut, "text/xml", call, http.StatusMethodNotAllowed},
+		{http.MethodPost, "application/json", call, http.StatusUnsupportedMediaType},
+		{http.MethodPost, "", call, http.StatusUnsupportedMediaType},
+		{http.MethodPost, "text/xml", call + strings.Repeat(" ", 100), http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+
+		s.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.status, rec.Code, test.method+" "+test.contentType)
+	}
+}
+
+func TestServeHTTPAllowsOnlyPost(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	newTestServer(t, torrentMethods).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestServeHTTPLimitsBodyWithoutContentLength(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	s.Limits = Limits{MaxBodyBytes: 50}
+	call := `<?xml version="1.0"?><methodCall><methodName>d.name</methodName></methodCall>`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(call))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "text/xml")
+	rec := httptest.NewRecorder()
+
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
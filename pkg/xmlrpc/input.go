@@ -0,0 +1,89 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"io"
+)
+
+const excerptSize = 48
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// input is the source of Decoder. It fails with LimitExceeded when more than max bytes read, tracks position
+// of the last read byte and keeps the recently read bytes for error messages. It implements io.ByteReader,
+// so xml.Decoder reads from it byte by byte without additional buffering, and position of input matches
+// position of xml.Decoder.
+type input struct {
+	r      byteReader
+	n      int64
+	max    int64
+	line   int
+	column int
+	recent [excerptSize]byte
+}
+
+func newInput(r io.Reader) *input {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &input{r: br, line: 1}
+}
+
+func (in *input) Read(p []byte) (n int, err error) {
+	if in.max > 0 {
+		if in.n >= in.max {
+			return in.overflow()
+		}
+		if int64(len(p)) > in.max-in.n {
+			p = p[0 : in.max-in.n]
+		}
+	}
+	n, err = in.r.Read(p)
+	for _, b := range p[:n] {
+		in.track(b)
+	}
+	return
+}
+
+func (in *input) ReadByte() (b byte, err error) {
+	if in.max > 0 && in.n >= in.max {
+		_, err = in.overflow()
+		return
+	}
+	if b, err = in.r.ReadByte(); err == nil {
+		in.track(b)
+	}
+	return
+}
+
+func (in *input) track(b byte) {
+	in.recent[in.n%excerptSize] = b
+	in.n++
+	if b == '\n' {
+		in.line++
+		in.column = 0
+	} else {
+		in.column++
+	}
+}
+
+// excerpt returns the recently read bytes.
+func (in *input) excerpt() string {
+	if in.n <= excerptSize {
+		return string(in.recent[:in.n])
+	}
+	i := in.n % excerptSize
+	return string(in.recent[i:]) + string(in.recent[:i])
+}
+
+// overflow checks whether the source has more data than allowed.
+func (in *input) overflow() (int, error) {
+	if _, err := in.r.ReadByte(); err != nil {
+		return 0, err
+	}
+	return 0, &LimitExceeded{Limit: "MaxBodyBytes", Max: in.max}
+}
@@ -0,0 +1,32 @@
+package xmlrpc
+
+import (
+	"context"
+)
+
+// Interceptor wraps calls of methods, e.g. for logging, metrics or authorization. It may change ctx and params
+// passed to next, return without calling next or change its result.
+type Interceptor func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error)
+
+// ChainInterceptors composes interceptors into one, the first interceptor is the outermost.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error) {
+		h := next
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			ic, inner := interceptors[i], h
+			h = func(ctx context.Context, params []interface{}) (interface{}, error) {
+				return ic(ctx, method, params, inner)
+			}
+		}
+		return h(ctx, params)
+	}
+}
+
+// Use adds interceptors which are applied to each call in order they are added, including calls of unknown
+// methods and calls inside system.multicall.
+func (s *Server) Use(interceptors ...Interceptor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// copy, so calls in progress keep using the previous chain
+	s.interceptors = append(s.interceptors[:len(s.interceptors):len(s.interceptors)], interceptors...)
+}
@@ -0,0 +1,91 @@
+package xmlrpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recording(name string, log *[]string) Interceptor {
+	return func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error) {
+		*log = append(*log, fmt.Sprintf("%s before %s", name, method))
+		res, err := next(ctx, params)
+		*log = append(*log, fmt.Sprintf("%s after %s", name, method))
+		return res, err
+	}
+}
+
+func TestInterceptorsAreCalledInOrder(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	var log []string
+	s.Use(recording("a", &log))
+	s.Use(recording("b", &log), recording("c", &log))
+
+	res, err := s.Call(context.Background(), "d.name", []interface{}{"h1"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ubuntu.iso", res)
+	assert.Equal(t, []string{
+		"a before d.name", "b before d.name", "c before d.name",
+		"c after d.name", "b after d.name", "a after d.name",
+	}, log)
+}
+
+func TestInterceptorCanChangeParamsAndShortCircuit(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	s.Use(func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error) {
+		if method == "d.open" {
+			return nil, NewFault(ApplicationError, "read only")
+		}
+		return next(ctx, append(params, "h1"))
+	})
+	ctx := context.Background()
+
+	res, err := s.Call(ctx, "d.name", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ubuntu.iso", res)
+
+	_, err = s.Call(ctx, "d.open", nil)
+	assert.Equal(t, NewFault(ApplicationError, "read only"), err)
+}
+
+func TestInterceptorsApplyToUnknownMethodsAndMulticall(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	var log []string
+	s.Use(recording("a", &log))
+	ctx := context.Background()
+
+	_, err := s.Call(ctx, "d.close", nil)
+	assert.True(t, IsMethodNotFound(err))
+
+	log = nil
+	calls := []interface{}{
+		map[string]interface{}{"methodName": "d.name", "params": []interface{}{"h1"}},
+		map[string]interface{}{"methodName": "d.open", "params": []interface{}{}},
+	}
+	_, err = s.Call(ctx, "system.multicall", []interface{}{calls})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"a before system.multicall",
+		"a before d.name", "a after d.name",
+		"a before d.open", "a after d.open",
+		"a after system.multicall",
+	}, log)
+}
+
+func TestChainInterceptors(t *testing.T) {
+	var log []string
+	chain := ChainInterceptors(recording("a", &log), recording("b", &log))
+	h := func(ctx context.Context, params []interface{}) (interface{}, error) {
+		log = append(log, "handler")
+		return len(params), nil
+	}
+
+	res, err := chain(context.Background(), "d.size", []interface{}{1, 2}, h)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res)
+	assert.Equal(t, []string{"a before d.size", "b before d.size", "handler", "b after d.size", "a after d.size"}, log)
+}
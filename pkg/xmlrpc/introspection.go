@@ -0,0 +1,77 @@
+package xmlrpc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+func (s *Server) registerIntrospection() {
+	s.methods["system.listMethods"] = &method{
+		h:          s.listMethods,
+		signatures: [][]string{{"array"}},
+		help:       "Returns names of all methods supported by the server which the caller may call.",
+	}
+	s.methods["system.methodSignature"] = &method{
+		h:          s.methodSignature,
+		signatures: [][]string{{"array", "string"}},
+		help: "Returns array of signatures of the method, the first element of signature is type of result, " +
+			"the rest are types of params. Returns \"undef\" if signatures are unknown.",
+	}
+	s.methods["system.methodHelp"] = &method{
+		h:          s.methodHelp,
+		signatures: [][]string{{"string", "string"}},
+		help:       "Returns description of the method.",
+	}
+}
+
+func (s *Server) listMethods(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) != 0 {
+		return nil, NewFault(InvalidParams, "system.listMethods expects no params")
+	}
+	s.mu.RLock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		if s.authorize(ctx, name) == nil {
+			names = append(names, name)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *Server) methodSignature(ctx context.Context, params []interface{}) (interface{}, error) {
+	m, err := s.introspected(ctx, "system.methodSignature", params)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.signatures) == 0 {
+		return "undef", nil
+	}
+	return m.signatures, nil
+}
+
+func (s *Server) methodHelp(ctx context.Context, params []interface{}) (interface{}, error) {
+	m, err := s.introspected(ctx, "system.methodHelp", params)
+	if err != nil {
+		return nil, err
+	}
+	return m.help, nil
+}
+
+// introspected finds method which name is the only param of introspection method, methods which caller
+// may not call are denied as in Call.
+func (s *Server) introspected(ctx context.Context, introspection string, params []interface{}) (*method, error) {
+	if len(params) != 1 {
+		return nil, NewFault(InvalidParams, fmt.Sprintf("%s expects method name", introspection))
+	}
+	name, ok := params[0].(string)
+	if !ok {
+		return nil, NewFault(InvalidParams, fmt.Sprintf("%s expects method name", introspection))
+	}
+	if err := s.authorize(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.lookup(name)
+}
@@ -0,0 +1,88 @@
+package xmlrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func introspectedServer(t *testing.T) *Server {
+	h := func(ctx context.Context, params []interface{}) (interface{}, error) { return nil, nil }
+	s := newTestServer(t, map[string]Handler{"d.erase": h})
+	assert.Nil(t, s.Register("d.size", h, WithSignature("i8", "string"), WithHelp("Returns size of torrent.")))
+	assert.Nil(t, s.RegisterService(&torrents{}, "t", WithMethodHelp("Name", "Returns name of torrent.")))
+	return s
+}
+
+func TestListMethods(t *testing.T) {
+	res, err := introspectedServer(t).Call(context.Background(), "system.listMethods", nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"d.erase", "d.size",
+		"system.getCapabilities", "system.listMethods", "system.methodHelp", "system.methodSignature", "system.multicall",
+		"t.getHTTPStatus", "t.name", "t.setFiles",
+	}, res)
+}
+
+func TestMethodSignature(t *testing.T) {
+	s := introspectedServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		method   string
+		expected interface{}
+	}{
+		{"d.size", [][]string{{"i8", "string"}}},
+		{"d.erase", "undef"},
+		{"t.name", [][]string{{"string", "string"}}},
+		{"t.setFiles", [][]string{{"int", "string", "array", "int"}}},
+		{"t.getHTTPStatus", [][]string{{"string"}}},
+		{"system.methodHelp", [][]string{{"string", "string"}}},
+	}
+	for _, test := range tests {
+		res, err := s.Call(ctx, "system.methodSignature", []interface{}{test.method})
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, res, test.method)
+	}
+}
+
+func TestMethodHelp(t *testing.T) {
+	s := introspectedServer(t)
+	ctx := context.Background()
+
+	res, err := s.Call(ctx, "system.methodHelp", []interface{}{"d.size"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Returns size of torrent.", res)
+
+	res, err = s.Call(ctx, "system.methodHelp", []interface{}{"t.name"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Returns name of torrent.", res)
+
+	res, err = s.Call(ctx, "system.methodHelp", []interface{}{"d.erase"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", res)
+}
+
+func TestIntrospectionFaults(t *testing.T) {
+	s := introspectedServer(t)
+	ctx := context.Background()
+
+	_, err := s.Call(ctx, "system.methodHelp", []interface{}{"d.open"})
+	assert.True(t, IsMethodNotFound(err))
+	_, err = s.Call(ctx, "system.methodSignature", nil)
+	assert.Equal(t, NewFault(InvalidParams, "system.methodSignature expects method name"), err)
+	_, err = s.Call(ctx, "system.listMethods", []interface{}{1})
+	assert.True(t, IsInvalidParams(err))
+}
+
+func TestIntrospectionOverXml(t *testing.T) {
+	call := `<?xml version="1.0"?><methodCall><methodName>system.methodSignature</methodName>
+<params><param><value><string>d.size</string></value></param></params></methodCall>`
+
+	res, err := serve(t, introspectedServer(t), call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{[]interface{}{"i8", "string"}}}, res)
+}
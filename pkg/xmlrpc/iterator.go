@@ -0,0 +1,111 @@
+package xmlrpc
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// ArrayIterator walks elements of <array> one at a time, so the whole array is never held in memory.
+//
+//	it, err := dec.DecodeResponseArray(0)
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		var row Torrent
+//		if err := it.Decode(&row); err != nil {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ArrayIterator struct {
+	dec     *Decoder
+	closer  io.Closer
+	index   int
+	pending bool
+	done    bool
+	err     error
+}
+
+// DecodeResponseArray reads <methodResponse> document up to the array found at path and returns iterator over
+// the array elements. The first element of path is index of the response param, default is 0. Next elements
+// are either int index of nested array element or string name of struct member.
+// If response contains <fault> the returned error is *Fault.
+func (dec *Decoder) DecodeResponseArray(path ...interface{}) (*ArrayIterator, error) {
+	if err := dec.u.seekResponseArray(dec.d, path); err != nil {
+		return nil, dec.wrap(err)
+	}
+	return &ArrayIterator{dec: dec}, nil
+}
+
+// Next advances to the next array element, it returns false when there are no more elements or error occurs.
+// Element that is not decoded with Decode is skipped.
+func (it *ArrayIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	u, d := &it.dec.u, it.dec.d
+	if it.pending {
+		it.pending = false
+		if _, err := u.unmarshalValueTo(d, reflect.Value{}); err != nil {
+			it.err = it.dec.wrap(err)
+			return false
+		}
+		u.pop()
+	}
+	se, err := u.startElement(d, "value")
+	if err != nil {
+		it.err = it.dec.wrap(err)
+		return false
+	}
+	if se == nil {
+		it.done = true
+		return false
+	}
+	if u.limits.MaxArrayLength > 0 && it.index >= u.limits.MaxArrayLength {
+		it.err = it.dec.wrap(&LimitExceeded{Limit: "MaxArrayLength", Max: int64(u.limits.MaxArrayLength)})
+		return false
+	}
+	u.unread(*se)
+	u.push(index(it.index))
+	it.index++
+	it.pending = true
+	return true
+}
+
+// Decode decodes current array element into v, v must be a non-nil pointer.
+func (it *ArrayIterator) Decode(v interface{}) error {
+	if it.err != nil {
+		return it.err
+	}
+	if !it.pending {
+		return errors.New("no current element, Next must be called before Decode")
+	}
+	target, err := targetOf(v)
+	if err != nil {
+		return err
+	}
+	it.pending = false
+	if _, err = it.dec.u.unmarshalValueTo(it.dec.d, target); err != nil {
+		it.err = it.dec.wrap(err)
+		return it.err
+	}
+	it.dec.u.pop()
+	return nil
+}
+
+// Err returns the first error that was encountered by the iterator.
+func (it *ArrayIterator) Err() error {
+	return it.err
+}
+
+// Close releases resources associated with the iterator, e.g. connection of client.
+func (it *ArrayIterator) Close() error {
+	it.done = true
+	if it.closer != nil {
+		return it.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package xmlrpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multicallResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><array><data>
+            <value><array><data>
+                <value><string>ubuntu.iso</string></value>
+                <value><i8>2097152</i8></value>
+                <value><boolean>1</boolean></value>
+            </data></array></value>
+            <value><array><data>
+                <value><string>debian.iso</string></value>
+                <value><i8>1048576</i8></value>
+                <value><boolean>0</boolean></value>
+            </data></array></value>
+            <value><array><data>
+                <value><string>fedora.iso</string></value>
+                <value><i8>4194304</i8></value>
+                <value><boolean>1</boolean></value>
+            </data></array></value>
+        </data></array></value></param>
+    </params>
+</methodResponse>
+`
+
+func TestIterateResponseArray(t *testing.T) {
+	it, err := NewDecoder(strings.NewReader(multicallResponse)).DecodeResponseArray()
+	assert.Nil(t, err)
+
+	var rows [][]interface{}
+	for it.Next() {
+		var row []interface{}
+		assert.Nil(t, it.Decode(&row))
+		rows = append(rows, row)
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, [][]interface{}{
+		{"ubuntu.iso", int64(2097152), true},
+		{"debian.iso", int64(1048576), false},
+		{"fedora.iso", int64(4194304), true},
+	}, rows)
+}
+
+func TestIterateResponseArraySkipsNotDecodedElements(t *testing.T) {
+	it, err := NewDecoder(strings.NewReader(multicallResponse)).DecodeResponseArray(0)
+	assert.Nil(t, err)
+
+	var names []string
+	for i := 0; it.Next(); i++ {
+		if i == 1 {
+			continue
+		}
+		var row [3]interface{}
+		assert.Nil(t, it.Decode(&row))
+		names = append(names, row[0].(string))
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"ubuntu.iso", "fedora.iso"}, names)
+}
+
+func TestIterateNestedArray(t *testing.T) {
+	it, err := NewDecoder(strings.NewReader(multicallResponse)).DecodeResponseArray(0, 1)
+	assert.Nil(t, err)
+
+	var values []interface{}
+	for it.Next() {
+		var v interface{}
+		assert.Nil(t, it.Decode(&v))
+		values = append(values, v)
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []interface{}{"debian.iso", int64(1048576), false}, values)
+}
+
+func TestIterateArrayInStructMember(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><struct>
+            <member>
+                <name>total</name>
+                <value><i4>2</i4></value>
+            </member>
+            <member>
+                <name>files</name>
+                <value><array><data>
+                    <value><struct>
+                        <member><name>path</name><value><string>a.txt</string></value></member>
+                        <member><name>size</name><value><i4>10</i4></value></member>
+                    </struct></value>
+                    <value><struct>
+                        <member><name>path</name><value><string>b.txt</string></value></member>
+                        <member><name>size</name><value><i4>20</i4></value></member>
+                        <member><name>priority</name><value><i4>1</i4></value></member>
+                    </struct></value>
+                </data></array></value>
+            </member>
+        </struct></value></param>
+    </params>
+</methodResponse>
+`
+	type file struct {
+		Path string `xmlrpc:"path"`
+		Size uint32
+	}
+
+	it, err := NewDecoder(strings.NewReader(xml)).DecodeResponseArray(0, "files")
+	assert.Nil(t, err)
+
+	var files []file
+	for it.Next() {
+		var f file
+		assert.Nil(t, it.Decode(&f))
+		files = append(files, f)
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []file{{"a.txt", 10}, {"b.txt", 20}}, files)
+}
+
+func TestIterateFaultResponse(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse><fault><value><struct>
+    <member><name>faultCode</name><value><i4>-501</i4></value></member>
+    <member><name>faultString</name><value><string>Unsupported target type found.</string></value></member>
+</struct></value></fault></methodResponse>
+`
+
+	_, err := NewDecoder(strings.NewReader(xml)).DecodeResponseArray()
+
+	assert.Equal(t, NewFault(-501, "Unsupported target type found."), err)
+}
+
+func TestIterateNotArray(t *testing.T) {
+	xml := `<?xml version="1.0"?><methodResponse><params><param><value><i4>1</i4></value></param></params></methodResponse>`
+
+	_, err := NewDecoder(strings.NewReader(xml)).DecodeResponseArray()
+
+	assert.Equal(t, "value at path [0] is not an array", causeOf(err).Error())
+}
+
+func TestIterateDecodeTypeMismatch(t *testing.T) {
+	it, err := NewDecoder(strings.NewReader(multicallResponse)).DecodeResponseArray()
+	assert.Nil(t, err)
+
+	assert.True(t, it.Next())
+	var row []int
+	assert.Equal(t, "cannot decode string into int", causeOf(it.Decode(&row)).Error())
+	assert.False(t, it.Next())
+	assert.NotNil(t, it.Err())
+}
@@ -0,0 +1,135 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrServerClosed is returned by ServeSCGI and ListenAndServeSCGI after Shutdown.
+var ErrServerClosed = errors.New("xmlrpc: server closed")
+
+// Shutdown closes SCGI listeners and idle SCGI connections, rejects new requests and waits until requests
+// in progress are served or ctx is done. HTTP requests are rejected with 503, so http.Server should be shut
+// down as well.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	s.listeners = nil
+	for c, active := range s.conns {
+		if !active {
+			c.Close()
+		}
+	}
+	s.mu.Unlock()
+	drained := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// begin registers request in progress, it returns false if server is shut down.
+func (s *Server) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.inFlight.Add(1)
+	return true
+}
+
+func (s *Server) end() {
+	s.inFlight.Done()
+}
+
+// trackConn registers accepted SCGI connection which is closed by Shutdown until request is read from it,
+// it returns false if server is shut down.
+func (s *Server) trackConn(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]bool)
+	}
+	s.conns[c] = false
+	return true
+}
+
+// beginConn is begin for request read from tracked connection c, Shutdown waits for it instead of closing c.
+func (s *Server) beginConn(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.inFlight.Add(1)
+	s.conns[c] = true
+	return true
+}
+
+func (s *Server) untrackConn(c net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+}
+
+// track registers listener closed by Shutdown, it returns false if server is shut down.
+func (s *Server) track(l net.Listener) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(l net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.listeners, l)
+}
+
+// acquire waits for free slot to run handler if number of concurrent calls is limited.
+func (s *Server) acquire(ctx context.Context) error {
+	if s.MaxConcurrentCalls <= 0 {
+		return nil
+	}
+	s.slotsOnce.Do(func() {
+		s.slots = make(chan struct{}, s.MaxConcurrentCalls)
+	})
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) release() {
+	if s.MaxConcurrentCalls > 0 {
+		<-s.slots
+	}
+}
@@ -0,0 +1,188 @@
+package xmlrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingServer returns server with method d.wait which blocks until release is closed.
+func blockingServer(t *testing.T) (s *Server, started chan struct{}, release chan struct{}) {
+	started = make(chan struct{}, 10)
+	release = make(chan struct{})
+	wait := func(ctx context.Context, params []interface{}) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return "done", nil
+	}
+	s = newTestServer(t, map[string]Handler{"d.wait": wait})
+	return
+}
+
+func TestHandlerTimeouts(t *testing.T) {
+	s := NewServer()
+	s.Timeout = time.Millisecond
+	waitDeadline := func(ctx context.Context, params []interface{}) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return "no deadline", nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	assert.Nil(t, s.Register("d.wait", waitDeadline))
+	assert.Nil(t, s.Register("d.waitLonger", waitDeadline, WithTimeout(time.Hour)))
+
+	_, err := serve(t, s, `<methodCall><methodName>d.wait</methodName></methodCall>`)
+	assert.Equal(t, NewFault(SystemError, "timeout"), err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = s.Call(ctx, "d.waitLonger", nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestServiceMethodTimeout(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.RegisterService(&torrents{}, "d", WithMethodTimeout("Name", time.Hour)))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	assert.Equal(t, time.Hour, s.methods["d.name"].timeout)
+	assert.Equal(t, time.Duration(0), s.methods["d.setFiles"].timeout)
+}
+
+func TestMaxConcurrentCalls(t *testing.T) {
+	s, started, release := blockingServer(t)
+	s.MaxConcurrentCalls = 1
+	call := `<methodCall><methodName>d.wait</methodName></methodCall>`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := serve(t, s, call)
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{"done"}, res)
+		}()
+	}
+	<-started
+	select {
+	case <-started:
+		t.Fatal("second call must wait for the first one")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.Call(ctx, "system.listMethods", nil)
+	assert.Nil(t, err, "calls without transport are not limited")
+	buf := &strings.Builder{}
+	assert.Nil(t, s.ServeCall(ctx, strings.NewReader(call), buf))
+	_, err = NewDecoder(strings.NewReader(buf.String())).DecodeResponse()
+	assert.Equal(t, NewFault(SystemError, "server is busy"), err)
+
+	close(release)
+	wg.Wait()
+}
+
+func TestShutdownClosesIdleSCGIConnections(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	l := serveSCGI(t, s, "tcp", "127.0.0.1:0")
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// wait until connection is accepted
+	for {
+		s.mu.RLock()
+		accepted := len(s.conns) == 1
+		s.mu.RUnlock()
+		if accepted {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, s.Shutdown(ctx))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestShutdownSCGI(t *testing.T) {
+	s, started, release := blockingServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ServeSCGI(l)
+	}()
+	result := make(chan interface{}, 1)
+	go func() {
+		res, err := CreateSCGIClient(l.Addr().String()).Send("d.wait")
+		assert.Nil(t, err)
+		result <- res
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+
+	assert.Equal(t, ErrServerClosed, <-served)
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown must wait for call in progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+	_, err = net.Dial("tcp", l.Addr().String())
+	assert.NotNil(t, err)
+
+	close(release)
+	assert.Nil(t, <-shutdown)
+	assert.Equal(t, []interface{}{"done"}, <-result)
+	assert.Equal(t, ErrServerClosed, s.ServeSCGI(l))
+}
+
+func TestShutdownHTTP(t *testing.T) {
+	s, started, release := blockingServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	call := `<methodCall><methodName>d.wait</methodName></methodCall>`
+	go func() {
+		resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(call))
+		if assert.Nil(t, err) {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(call))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	close(release)
+	assert.Nil(t, s.Shutdown(context.Background()))
+}
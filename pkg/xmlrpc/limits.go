@@ -0,0 +1,51 @@
+package xmlrpc
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits restricts documents accepted by Decoder, it protects from exhausting memory or stack by misbehaving
+// or malicious peer. Zero value of a field means no limit.
+type Limits struct {
+	// MaxBodyBytes is max size of the whole document.
+	MaxBodyBytes int64
+	// MaxDepth is max nesting of arrays and structs.
+	MaxDepth int
+	// MaxArrayLength is max number of elements in an array.
+	MaxArrayLength int
+	// MaxStructMembers is max number of members in a struct.
+	MaxStructMembers int
+	// MaxStringBytes is max size of text of a scalar value, struct member name or method name.
+	MaxStringBytes int
+	// MaxBase64Bytes is max size of decoded content of <base64> value.
+	MaxBase64Bytes int64
+}
+
+// LimitExceeded is returned by Decoder when document exceeds one of the Limits.
+type LimitExceeded struct {
+	// Limit is name of exceeded limit, e.g. MaxDepth.
+	Limit string
+	Max   int64
+}
+
+func (e *LimitExceeded) Error() string {
+	return fmt.Sprintf("limit exceeded: %s %d", e.Limit, e.Max)
+}
+
+// limitedWriter fails with LimitExceeded when more than max bytes written to it.
+type limitedWriter struct {
+	w     io.Writer
+	n     int64
+	max   int64
+	limit string
+}
+
+func (lw *limitedWriter) Write(p []byte) (n int, err error) {
+	if lw.max > 0 && lw.n+int64(len(p)) > lw.max {
+		return 0, &LimitExceeded{Limit: lw.limit, Max: lw.max}
+	}
+	n, err = lw.w.Write(p)
+	lw.n += int64(n)
+	return
+}
@@ -0,0 +1,129 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeWithLimits(xml string, l Limits) ([]interface{}, error) {
+	dec := NewDecoder(strings.NewReader(xml))
+	dec.SetLimits(l)
+	return dec.DecodeResponse()
+}
+
+const nestedArraysResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse><params><param><value><array><data>
+    <value><array><data>
+        <value><array><data>
+            <value><i4>1</i4></value>
+        </data></array></value>
+    </data></array></value>
+</data></array></value></param></params></methodResponse>`
+
+func TestLimitMaxBodyBytes(t *testing.T) {
+	_, err := decodeWithLimits(nestedArraysResponse, Limits{MaxBodyBytes: 100})
+
+	assert.Equal(t, &LimitExceeded{Limit: "MaxBodyBytes", Max: 100}, causeOf(err))
+}
+
+func TestLimitMaxBodyBytesNotExceeded(t *testing.T) {
+	res, err := decodeWithLimits(nestedArraysResponse, Limits{MaxBodyBytes: int64(len(nestedArraysResponse))})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{[]interface{}{[]interface{}{1}}}}, res)
+}
+
+func TestLimitMaxDepth(t *testing.T) {
+	_, err := decodeWithLimits(nestedArraysResponse, Limits{MaxDepth: 2})
+	assert.Equal(t, &LimitExceeded{Limit: "MaxDepth", Max: 2}, causeOf(err))
+
+	_, err = decodeWithLimits(nestedArraysResponse, Limits{MaxDepth: 3})
+	assert.Nil(t, err)
+}
+
+func TestLimitMaxArrayLength(t *testing.T) {
+	xml := `<methodResponse><params><param><value><array><data>
+    <value><i4>1</i4></value>
+    <value><i4>2</i4></value>
+    <value><i4>3</i4></value>
+</data></array></value></param></params></methodResponse>`
+
+	_, err := decodeWithLimits(xml, Limits{MaxArrayLength: 2})
+	assert.Equal(t, &LimitExceeded{Limit: "MaxArrayLength", Max: 2}, causeOf(err))
+
+	res, err := decodeWithLimits(xml, Limits{MaxArrayLength: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{1, 2, 3}}, res)
+}
+
+func TestLimitMaxArrayLengthOfIteratedArray(t *testing.T) {
+	xml := `<methodResponse><params><param><value><array><data>
+    <value><i4>1</i4></value>
+    <value><i4>2</i4></value>
+    <value><i4>3</i4></value>
+</data></array></value></param></params></methodResponse>`
+	iterate := func(max int) (n int, err error) {
+		dec := NewDecoder(strings.NewReader(xml))
+		dec.SetLimits(Limits{MaxArrayLength: max})
+		it, err := dec.DecodeResponseArray()
+		if err != nil {
+			return
+		}
+		for it.Next() {
+			n++
+		}
+		err = it.Err()
+		return
+	}
+
+	n, err := iterate(2)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, &LimitExceeded{Limit: "MaxArrayLength", Max: 2}, causeOf(err))
+
+	n, err = iterate(3)
+	assert.Equal(t, 3, n)
+	assert.Nil(t, err)
+}
+
+func TestLimitMaxStructMembers(t *testing.T) {
+	xml := `<methodResponse><params><param><value><struct>
+    <member><name>a</name><value><i4>1</i4></value></member>
+    <member><name>b</name><value><i4>2</i4></value></member>
+</struct></value></param></params></methodResponse>`
+
+	_, err := decodeWithLimits(xml, Limits{MaxStructMembers: 1})
+	assert.Equal(t, &LimitExceeded{Limit: "MaxStructMembers", Max: 1}, causeOf(err))
+
+	_, err = decodeWithLimits(xml, Limits{MaxStructMembers: 2})
+	assert.Nil(t, err)
+}
+
+func TestLimitMaxStringBytes(t *testing.T) {
+	xml := `<methodResponse><params><param><value><string>hello</string></value></param></params></methodResponse>`
+
+	_, err := decodeWithLimits(xml, Limits{MaxStringBytes: 4})
+	assert.Equal(t, &LimitExceeded{Limit: "MaxStringBytes", Max: 4}, causeOf(err))
+
+	_, err = decodeWithLimits(xml, Limits{MaxStringBytes: 5})
+	assert.Nil(t, err)
+}
+
+func TestLimitMaxBase64Bytes(t *testing.T) {
+	xml := `<methodResponse><params><param><value><base64>aGVsbG8=</base64></value></param></params></methodResponse>`
+
+	_, err := decodeWithLimits(xml, Limits{MaxBase64Bytes: 4})
+	assert.Equal(t, &LimitExceeded{Limit: "MaxBase64Bytes", Max: 4}, causeOf(err))
+
+	buf := &bytes.Buffer{}
+	dec := NewDecoder(strings.NewReader(xml))
+	dec.SetLimits(Limits{MaxBase64Bytes: 4})
+	err = dec.DecodeResponseInto(buf)
+	assert.Equal(t, &LimitExceeded{Limit: "MaxBase64Bytes", Max: 4}, causeOf(err))
+
+	res, err := decodeWithLimits(xml, Limits{MaxBase64Bytes: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]byte("hello")}, res)
+}
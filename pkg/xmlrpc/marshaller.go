@@ -6,160 +6,421 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"unicode/utf8"
 )
 
 type marshaller struct {
+	// charset of produced documents, nil means UTF-8
+	charset      *charset
+	invalidChars InvalidCharPolicy
+	// nil values are written as <nil/> if true
+	nilValues bool
+	// content of Base64Stream isn't read but replaced with placeholder of the same size if true,
+	// it's used to compute size of document before it's sent
+	sizeOnly bool
+}
+
+// InvalidCharPolicy defines how strings that contain characters not allowed in XML 1.0, e.g. \x00,
+// or invalid UTF-8 are encoded.
+type InvalidCharPolicy int
+
+const (
+	// ReplaceInvalidChars replaces such characters with U+FFFD, it's default.
+	ReplaceInvalidChars InvalidCharPolicy = iota
+	// RejectInvalidChars fails encoding.
+	RejectInvalidChars
+	// Base64InvalidChars writes such strings as <base64> value with the original bytes. Struct member name
+	// can't be written as <base64>, so encoding fails if member name contains such characters.
+	Base64InvalidChars
+)
+
+// Base64Stream is an argument which content is read from the reader and written as <base64> value
+// without loading it into memory. Client has to know size of request before it's sent, so the request is
+// loaded into memory if size of content isn't known, i.e. if the reader is neither io.Seeker, e.g. *os.File,
+// nor has method Len() int, e.g. *bytes.Reader.
+type Base64Stream struct {
+	io.Reader
 }
 
 func (m *marshaller) marshal(method string, args ...interface{}) (xml []byte, err error) {
-	xmlWr := bytes.NewBufferString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>")
-	if _, err = xmlWr.WriteString("<methodCall><methodName>"); err != nil {
+	xmlWr := &bytes.Buffer{}
+	if err = m.marshalCall(xmlWr, method, args...); err != nil {
+		return
+	}
+	xml = xmlWr.Bytes()
+	return
+}
+
+func (m *marshaller) marshalCall(w io.Writer, method string, args ...interface{}) (err error) {
+	if err = validateMethodName(method); err != nil {
+		return
+	}
+	w = m.encoding(w)
+	if err = m.header(w); err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, "<methodCall><methodName>"); err != nil {
+		return
+	}
+	if err = xml.EscapeText(w, []byte(method)); err != nil {
 		return
 	}
-	if _, err = xmlWr.WriteString(method); err != nil {
+	if _, err = io.WriteString(w, "</methodName>"); err != nil {
 		return
 	}
-	if _, err = xmlWr.WriteString("</methodName>"); err != nil {
+	if err = m.marshalParams(w, args...); err != nil {
 		return
 	}
-	if _, err = xmlWr.WriteString("<params>"); err != nil {
+	_, err = io.WriteString(w, "</methodCall>")
+	return
+}
+
+func (m *marshaller) marshalResponse(w io.Writer, params ...interface{}) (err error) {
+	w = m.encoding(w)
+	if err = m.header(w); err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, "<methodResponse>"); err != nil {
 		return
 	}
-	for _, arg := range args {
-		if _, err = xmlWr.WriteString("<param>"); err != nil {
+	if err = m.marshalParams(w, params...); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "</methodResponse>")
+	return
+}
+
+func (m *marshaller) marshalFault(w io.Writer, f *Fault) (err error) {
+	w = m.encoding(w)
+	if err = m.header(w); err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, "<methodResponse><fault>"); err != nil {
+		return
+	}
+	fm := map[string]interface{}{"faultCode": f.Code, "faultString": f.Text}
+	if err = m.marshalValue(w, fm); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "</fault></methodResponse>")
+	return
+}
+
+func (m *marshaller) header(w io.Writer) (err error) {
+	name := "UTF-8"
+	if m.charset != nil {
+		name = m.charset.name
+	}
+	_, err = fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"%s\"?>", name)
+	return
+}
+
+func (m *marshaller) encoding(w io.Writer) io.Writer {
+	if m.charset == nil {
+		return w
+	}
+	return &charsetEncoder{w: w, cs: m.charset}
+}
+
+func (m *marshaller) marshalParams(w io.Writer, params ...interface{}) (err error) {
+	if _, err = io.WriteString(w, "<params>"); err != nil {
+		return
+	}
+	for _, p := range params {
+		if _, err = io.WriteString(w, "<param>"); err != nil {
 			return
 		}
-		if err = marshalValue(xmlWr, arg); err != nil {
+		if err = m.marshalValue(w, p); err != nil {
 			return
 		}
-		if _, err = xmlWr.WriteString("</param>"); err != nil {
+		if _, err = io.WriteString(w, "</param>"); err != nil {
 			return
 		}
 	}
-	_, err = xmlWr.WriteString("</params></methodCall>")
-
-	xml = xmlWr.Bytes()
+	_, err = io.WriteString(w, "</params>")
 	return
 }
 
-func marshalValue(buf *bytes.Buffer, v interface{}) (err error) {
-	if _, err = buf.WriteString("<value>"); err != nil {
+func (m *marshaller) marshalValue(w io.Writer, v interface{}) (err error) {
+	if _, err = io.WriteString(w, "<value>"); err != nil {
 		return
 	}
-	err = marshalType(buf, v)
+	err = m.marshalType(w, v)
 	if err == nil {
-		_, err = buf.WriteString("</value>")
-	}
-	return
-}
-
-func marshalType(buf *bytes.Buffer, v interface{}) (err error) {
-	t := reflect.TypeOf(v)
-	b, ok := v.([]byte)
-	if ok {
-		_, err = buf.WriteString(fmt.Sprintf("<base64>%s</base64>", base64.StdEncoding.EncodeToString(b)))
-		return
-	}
-	if t != nil {
-		switch t.Kind() {
-		case reflect.String:
-			if _, err = buf.WriteString("<string>"); err != nil {
-				return
-			}
-			if err = xml.EscapeText(buf, []byte(v.(string))); err != nil {
-				return
-			}
-			_, err = buf.WriteString("</string>")
-		case reflect.Bool:
-			_, err = buf.WriteString(fmt.Sprintf("<boolean>%d</boolean>", asInt(v.(bool))))
-		case reflect.Int:
-			_, err = buf.WriteString(fmt.Sprintf("<i4>%d</i4>", v.(int)))
-		case reflect.Int64:
-			_, err = buf.WriteString(fmt.Sprintf("<i8>%d</i8>", v.(int64)))
-		case reflect.Slice:
-			err = marshalArray(buf, v.([]interface{}))
-		case reflect.Map:
-			err = marshalMap(buf, v.(map[string]interface{}))
-		case reflect.Struct:
-			err = marshalStruct(buf, t, v)
-		case reflect.Ptr:
-			err = marshalValue(buf, reflect.Indirect(reflect.ValueOf(v)).Interface())
-		default:
-			err = errors.New(fmt.Sprintf("unsupported type: %v", t))
-		}
+		_, err = io.WriteString(w, "</value>")
 	}
 	return
 }
 
-func marshalArray(buf *bytes.Buffer, arr []interface{}) (err error) {
-	if _, err = buf.WriteString("<array><data>"); err != nil {
+func (m *marshaller) marshalType(w io.Writer, v interface{}) (err error) {
+	switch b := v.(type) {
+	case nil:
+		if m.nilValues {
+			_, err = io.WriteString(w, "<nil/>")
+		}
+		return
+	case []byte:
+		_, err = fmt.Fprintf(w, "<base64>%s</base64>", base64.StdEncoding.EncodeToString(b))
+		return
+	case Base64Stream:
+		err = m.marshalBase64Stream(w, b)
 		return
 	}
-	for _, e := range arr {
-		if err = marshalValue(buf, e); err != nil {
-			break
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		err = m.marshalString(w, rv.String())
+	case reflect.Bool:
+		_, err = fmt.Fprintf(w, "<boolean>%d</boolean>", asInt(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		err = marshalInt(w, rv.Int())
+	case reflect.Int64:
+		_, err = fmt.Fprintf(w, "<i8>%d</i8>", rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			err = errors.New(fmt.Sprintf("value %d overflows i8", rv.Uint()))
+		} else if rv.Kind() == reflect.Uint64 {
+			_, err = fmt.Fprintf(w, "<i8>%d</i8>", rv.Uint())
+		} else {
+			err = marshalInt(w, int64(rv.Uint()))
+		}
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			err = m.marshalType(w, rv.Bytes())
+		} else {
+			err = m.marshalArray(w, rv)
 		}
+	case reflect.Array:
+		err = m.marshalArray(w, rv)
+	case reflect.Map:
+		err = m.marshalMap(w, rv)
+	case reflect.Struct:
+		err = m.marshalStruct(w, rv)
+	case reflect.Ptr:
+		if rv.IsNil() && m.nilValues {
+			_, err = io.WriteString(w, "<nil/>")
+		} else if rv.IsNil() {
+			err = errors.New(fmt.Sprintf("cannot encode nil %v", rv.Type()))
+		} else {
+			err = m.marshalType(w, rv.Elem().Interface())
+		}
+	default:
+		err = errors.New(fmt.Sprintf("unsupported type: %v", rv.Type()))
 	}
-	_, err = buf.WriteString("</data></array>")
 	return
 }
 
-func marshalMap(buf *bytes.Buffer, m map[string]interface{}) (err error) {
-	if _, err = buf.WriteString("<struct>"); err != nil {
-		return
+// marshalInt writes i as <i4> if it fits 32 bits, otherwise as <i8>.
+func marshalInt(w io.Writer, i int64) (err error) {
+	if i >= math.MinInt32 && i <= math.MaxInt32 {
+		_, err = fmt.Fprintf(w, "<i4>%d</i4>", i)
+	} else {
+		_, err = fmt.Fprintf(w, "<i8>%d</i8>", i)
 	}
-	for k, v := range m {
-		if _, err = buf.WriteString("<member>"); err != nil {
+	return
+}
+
+func (m *marshaller) marshalString(w io.Writer, s string) (err error) {
+	if !isValidText(s) {
+		switch m.invalidChars {
+		case RejectInvalidChars:
+			err = errors.New(fmt.Sprintf("string %q contains characters not allowed in XML", s))
 			return
-		}
-		if _, err = buf.WriteString("<name>"); err != nil {
+		case Base64InvalidChars:
+			_, err = fmt.Fprintf(w, "<base64>%s</base64>", base64.StdEncoding.EncodeToString([]byte(s)))
 			return
 		}
-		if err = xml.EscapeText(buf, []byte(k)); err != nil {
+	}
+	if _, err = io.WriteString(w, "<string>"); err != nil {
+		return
+	}
+	if err = xml.EscapeText(w, []byte(s)); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "</string>")
+	return
+}
+
+func (m *marshaller) marshalBase64Stream(w io.Writer, s Base64Stream) (err error) {
+	if _, err = io.WriteString(w, "<base64>"); err != nil {
+		return
+	}
+	if m.sizeOnly {
+		var n int64
+		if n, err = streamSize(s.Reader); err != nil {
 			return
 		}
-		if _, err = buf.WriteString("</name>"); err != nil {
+		if err = writePlaceholder(w, (n+2)/3*4); err != nil {
 			return
 		}
-		if err = marshalValue(buf, v); err != nil {
+		_, err = io.WriteString(w, "</base64>")
+		return
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err = io.Copy(enc, s.Reader); err != nil {
+		return
+	}
+	if err = enc.Close(); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "</base64>")
+	return
+}
+
+var errUnknownSize = errors.New("size of stream is unknown")
+
+// streamSize returns number of bytes left in r without reading it, or errUnknownSize.
+func streamSize(r io.Reader) (n int64, err error) {
+	switch sr := r.(type) {
+	case interface{ Len() int }:
+		n = int64(sr.Len())
+		return
+	case io.Seeker:
+		var cur int64
+		if cur, err = sr.Seek(0, io.SeekCurrent); err != nil {
 			return
 		}
-		if _, err = buf.WriteString("</member>"); err != nil {
+		if n, err = sr.Seek(0, io.SeekEnd); err != nil {
 			return
 		}
+		n -= cur
+		_, err = sr.Seek(cur, io.SeekStart)
+		return
+	}
+	err = errUnknownSize
+	return
+}
+
+var placeholder = bytes.Repeat([]byte{'A'}, 4096)
+
+func writePlaceholder(w io.Writer, n int64) (err error) {
+	for n > 0 && err == nil {
+		chunk := placeholder
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		_, err = w.Write(chunk)
+		n -= int64(len(chunk))
 	}
-	_, err = buf.WriteString("</struct>")
 	return
 }
 
-func marshalStruct(buf *bytes.Buffer, t reflect.Type, st interface{}) (err error) {
-	v := reflect.ValueOf(st)
-	if _, err = buf.WriteString("<struct>"); err != nil {
+func (m *marshaller) marshalArray(w io.Writer, arr reflect.Value) (err error) {
+	if _, err = io.WriteString(w, "<array><data>"); err != nil {
 		return
 	}
-	for i := 0; i < t.NumField(); i++ {
-		if _, err = buf.WriteString("<member>"); err != nil {
+	for i := 0; i < arr.Len(); i++ {
+		if err = m.marshalValue(w, arr.Index(i).Interface()); err != nil {
 			return
 		}
-		if _, err = buf.WriteString("<name>"); err != nil {
+	}
+	_, err = io.WriteString(w, "</data></array>")
+	return
+}
+
+// marshalMap writes map with string keys as <struct>, members are sorted by name.
+func (m *marshaller) marshalMap(w io.Writer, mp reflect.Value) (err error) {
+	if mp.Type().Key().Kind() != reflect.String {
+		err = errors.New(fmt.Sprintf("unsupported type: %v, map key must be string", mp.Type()))
+		return
+	}
+	keys := mp.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	if _, err = io.WriteString(w, "<struct>"); err != nil {
+		return
+	}
+	for _, k := range keys {
+		if err = m.marshalMember(w, k.String(), mp.MapIndex(k).Interface()); err != nil {
 			return
 		}
-		if err = xml.EscapeText(buf, []byte(t.Field(i).Name)); err != nil {
-			return
+	}
+	_, err = io.WriteString(w, "</struct>")
+	return
+}
+
+func (m *marshaller) marshalStruct(w io.Writer, st reflect.Value) (err error) {
+	t := st.Type()
+	if _, err = io.WriteString(w, "<struct>"); err != nil {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := fieldName(t.Field(i))
+		if !ok {
+			continue
 		}
-		if _, err = buf.WriteString("</name>"); err != nil {
+		if err = m.marshalMember(w, name, st.Field(i).Interface()); err != nil {
 			return
 		}
-		if err = marshalValue(buf, v.FieldByIndex([]int{i}).Interface()); err != nil {
-			return
+	}
+	_, err = io.WriteString(w, "</struct>")
+	return
+}
+
+func (m *marshaller) marshalMember(w io.Writer, name string, v interface{}) (err error) {
+	if m.invalidChars != ReplaceInvalidChars && !isValidText(name) {
+		err = errors.New(fmt.Sprintf("struct member name %q contains characters not allowed in XML", name))
+		return
+	}
+	if _, err = io.WriteString(w, "<member>"); err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, "<name>"); err != nil {
+		return
+	}
+	if err = xml.EscapeText(w, []byte(name)); err != nil {
+		return
+	}
+	if _, err = io.WriteString(w, "</name>"); err != nil {
+		return
+	}
+	if err = m.marshalValue(w, v); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, "</member>")
+	return
+}
+
+// validateMethodName checks that method name contains only characters allowed by the specification:
+// A-Z, a-z, 0-9, underscore, dot, colon and slash.
+func validateMethodName(method string) error {
+	if method == "" {
+		return errors.New("invalid method name, it must not be empty")
+	}
+	for _, c := range method {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '_', c == '.', c == ':', c == '/':
+		default:
+			return errors.New(fmt.Sprintf("invalid method name %q, character %q is not allowed", method, c))
 		}
-		if _, err = buf.WriteString("</member>"); err != nil {
-			return
+	}
+	return nil
+}
+
+// isValidText checks that s is valid UTF-8 and contains only characters allowed in XML 1.0:
+// #x9 | #xA | #xD | [#x20-#xD7FF] | [#xE000-#xFFFD] | [#x10000-#x10FFFF]
+func isValidText(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return false
+		}
+		i += size
+		if r == 0x09 || r == 0x0A || r == 0x0D ||
+			r >= 0x20 && r <= 0xD7FF ||
+			r >= 0xE000 && r <= 0xFFFD ||
+			r >= 0x10000 && r <= 0x10FFFF {
+			continue
 		}
+		return false
 	}
-	_, err = buf.WriteString("</struct>")
-	return
+	return true
 }
 
 func asInt(b bool) int {
@@ -169,4 +430,3 @@ func asInt(b bool) int {
 		return 0
 	}
 }
-
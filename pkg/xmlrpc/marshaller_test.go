@@ -1,6 +1,8 @@
 package xmlrpc
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/go-xmlfmt/xmlfmt"
@@ -48,6 +50,21 @@ func TestMarshalWithSingleBytesParam(t *testing.T) {
 	assert.Equal(t, expected, formatXml(string(xml)))
 }
 
+func TestMarshalWithBase64StreamParam(t *testing.T) {
+	m := marshaller{}
+	xml, err := m.marshal("load.raw_start", "", Base64Stream{strings.NewReader("hello")})
+	assert.Nil(t, err)
+	expected := formatXml(`<?xml version="1.0" encoding="UTF-8"?>
+<methodCall>
+    <methodName>load.raw_start</methodName>
+    <params>
+        <param><value><string></string></value></param>
+        <param><value><base64>aGVsbG8=</base64></value></param>
+    </params>
+</methodCall>`)
+	assert.Equal(t, expected, formatXml(string(xml)))
+}
+
 func TestMarshalWithSingleBooleanParam(t *testing.T) {
 	m := marshaller{}
 	xml, err := m.marshal("yesOrNo", []interface{}{true}...)
@@ -229,3 +246,30 @@ func TestMarshalWithMultipleParams(t *testing.T) {
 func formatXml(in string) string {
 	return xmlfmt.FormatXML(in, "", "  ")
 }
+
+func TestMarshalValidatesMethodName(t *testing.T) {
+	m := marshaller{}
+
+	_, err := m.marshal("system.listMethods")
+	assert.Nil(t, err)
+	_, err = m.marshal("ns:group/method_2")
+	assert.Nil(t, err)
+
+	_, err = m.marshal("")
+	assert.Equal(t, "invalid method name, it must not be empty", err.Error())
+	_, err = m.marshal("evil</methodName><methodName>x")
+	assert.Equal(t, `invalid method name "evil</methodName><methodName>x", character '<' is not allowed`, err.Error())
+	_, err = m.marshal("a&b")
+	assert.Equal(t, `invalid method name "a&b", character '&' is not allowed`, err.Error())
+	_, err = m.marshal("d.name=")
+	assert.Equal(t, `invalid method name "d.name=", character '=' is not allowed`, err.Error())
+}
+
+func TestEncoderWritesNothingForInvalidMethodName(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	err := NewEncoder(buf).EncodeCall("bad name")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buf.Len())
+}
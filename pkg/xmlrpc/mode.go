@@ -0,0 +1,21 @@
+package xmlrpc
+
+// Mode defines how strictly Decoder follows the XML-RPC specification.
+type Mode int
+
+const (
+	// Lenient mode is default, it accepts common deviations from the specification:
+	//   - boolean as 1, t, T, TRUE, true, True, 0, f, F, FALSE, false or False,
+	//   - spaces around int, i4, i8 and boolean values,
+	//   - spaces and line breaks inside base64 value,
+	//   - any number of params in response,
+	//   - unknown elements, they are skipped.
+	Lenient Mode = iota
+	// Strict mode follows the specification exactly:
+	//   - boolean is only 0 or 1,
+	//   - no spaces around int, i4, i8 and boolean values,
+	//   - only line breaks inside base64 value,
+	//   - response contains exactly one param,
+	//   - no unknown elements.
+	Strict
+)
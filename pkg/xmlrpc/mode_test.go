@@ -0,0 +1,99 @@
+package xmlrpc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeWithMode(xml string, m Mode) ([]interface{}, error) {
+	dec := NewDecoder(strings.NewReader(xml))
+	dec.SetMode(m)
+	return dec.DecodeResponse()
+}
+
+func response(values ...string) string {
+	var params string
+	for _, v := range values {
+		params += "<param><value>" + v + "</value></param>"
+	}
+	return `<?xml version="1.0"?><methodResponse><params>` + params + `</params></methodResponse>`
+}
+
+func TestLenientBoolean(t *testing.T) {
+	res, err := decodeWithMode(response("<boolean>true</boolean>", "<boolean> FALSE </boolean>", "<boolean>1</boolean>"), Lenient)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{true, false, true}, res)
+}
+
+func TestStrictBoolean(t *testing.T) {
+	res, err := decodeWithMode(response("<boolean>0</boolean>"), Strict)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{false}, res)
+
+	_, err = decodeWithMode(response("<boolean>true</boolean>"), Strict)
+	assert.Equal(t, `invalid boolean: "true"`, causeOf(err).Error())
+}
+
+func TestLenientPaddedIntegers(t *testing.T) {
+	res, err := decodeWithMode(response("<i4> 12 </i4>", "<int>\n-3\n</int>", "<i8>\t42</i8>"), Lenient)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{12, -3, int64(42)}, res)
+}
+
+func TestStrictPaddedIntegers(t *testing.T) {
+	_, err := decodeWithMode(response("<i4> 12 </i4>"), Strict)
+
+	assert.NotNil(t, err)
+}
+
+func TestLenientBase64WithSpaces(t *testing.T) {
+	res, err := decodeWithMode(response("<base64> aGVs bG8= </base64>"), Lenient)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]byte("hello")}, res)
+}
+
+func TestStrictBase64(t *testing.T) {
+	res, err := decodeWithMode(response("<base64>aGVs\r\nbG8=</base64>"), Strict)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]byte("hello")}, res)
+
+	_, err = decodeWithMode(response("<base64> aGVsbG8=</base64>"), Strict)
+	assert.Equal(t, "invalid base64, only line breaks are allowed", causeOf(err).Error())
+}
+
+func TestLenientSkipsUnknownElements(t *testing.T) {
+	xml := `<?xml version="1.0"?>
+<methodResponse>
+    <params>
+        <param><value><struct>
+            <member><name>msg</name><meta>ignored</meta><value><string>hello</string></value></member>
+            <extra><value><i4>1</i4></value></extra>
+        </struct></value></param>
+        <debug>x</debug>
+    </params>
+</methodResponse>`
+
+	res, err := decodeWithMode(xml, Lenient)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"msg": "hello"}}, res)
+
+	_, err = decodeWithMode(xml, Strict)
+	assert.NotNil(t, err)
+}
+
+func TestStrictRequiresSingleParam(t *testing.T) {
+	_, err := decodeWithMode(response("<i4>1</i4>", "<i4>2</i4>"), Strict)
+	assert.Equal(t, "response must contain exactly one param, got 2", causeOf(err).Error())
+
+	_, err = decodeWithMode(response(), Strict)
+	assert.Equal(t, "response must contain exactly one param, got 0", causeOf(err).Error())
+
+	res, err := decodeWithMode(response("<i4>1</i4>", "<i4>2</i4>"), Lenient)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1, 2}, res)
+}
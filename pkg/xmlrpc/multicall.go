@@ -0,0 +1,61 @@
+package xmlrpc
+
+import (
+	"context"
+	"fmt"
+)
+
+func (s *Server) registerMulticall() {
+	s.methods["system.multicall"] = &method{
+		h:          s.multicall,
+		signatures: [][]string{{"array", "array"}},
+		help: "Processes array of calls, each call is struct with members methodName and params. Returns array " +
+			"which contains either array with result of call or fault struct for each call.",
+	}
+}
+
+func (s *Server) multicall(ctx context.Context, params []interface{}) (interface{}, error) {
+	if len(params) != 1 {
+		return nil, NewFault(InvalidParams, "system.multicall expects array of calls")
+	}
+	calls, ok := params[0].([]interface{})
+	if !ok {
+		return nil, NewFault(InvalidParams, "system.multicall expects array of calls")
+	}
+	if s.MaxMulticallSize > 0 && len(calls) > s.MaxMulticallSize {
+		return nil, NewFault(InvalidParams,
+			fmt.Sprintf("system.multicall accepts at most %d calls, got %d", s.MaxMulticallSize, len(calls)))
+	}
+	results := make([]interface{}, len(calls))
+	for i, c := range calls {
+		result, err := s.multicallEntry(ctx, c)
+		if err != nil {
+			f := s.faultOf(err)
+			results[i] = map[string]interface{}{"faultCode": f.Code, "faultString": f.Text}
+		} else {
+			results[i] = []interface{}{result}
+		}
+	}
+	return results, nil
+}
+
+func (s *Server) multicallEntry(ctx context.Context, c interface{}) (interface{}, error) {
+	call, ok := c.(map[string]interface{})
+	if !ok {
+		return nil, NewFault(InvalidParams, "call must be struct with members methodName and params")
+	}
+	name, ok := call["methodName"].(string)
+	if !ok {
+		return nil, NewFault(InvalidParams, "call must have string member methodName")
+	}
+	if name == "system.multicall" {
+		return nil, NewFault(InvalidParams, "recursive system.multicall is not allowed")
+	}
+	var params []interface{}
+	if p, found := call["params"]; found {
+		if params, ok = p.([]interface{}); !ok {
+			return nil, NewFault(InvalidParams, "params of call must be array")
+		}
+	}
+	return s.Call(ctx, name, params)
+}
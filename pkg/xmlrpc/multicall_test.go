@@ -0,0 +1,68 @@
+package xmlrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulticall(t *testing.T) {
+	call := `<?xml version="1.0"?>
+<methodCall>
+    <methodName>system.multicall</methodName>
+    <params><param><value><array><data>
+        <value><struct>
+            <member><name>methodName</name><value>d.name</value></member>
+            <member><name>params</name><value><array><data><value>h1</value></data></array></value></member>
+        </struct></value>
+        <value><struct>
+            <member><name>methodName</name><value>d.open</value></member>
+            <member><name>params</name><value><array><data></data></array></value></member>
+        </struct></value>
+        <value><struct>
+            <member><name>methodName</name><value>d.close</value></member>
+        </struct></value>
+    </data></array></value></param></params>
+</methodCall>`
+
+	res, err := serve(t, newTestServer(t, torrentMethods), call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{
+		[]interface{}{"ubuntu.iso"},
+		map[string]interface{}{"faultCode": ApplicationError, "faultString": "application error"},
+		map[string]interface{}{"faultCode": MethodNotFound, "faultString": "method not found: d.close"},
+	}}, res)
+}
+
+func TestMulticallRejectsInvalidEntries(t *testing.T) {
+	calls := []interface{}{
+		"d.name",
+		map[string]interface{}{"params": []interface{}{}},
+		map[string]interface{}{"methodName": "d.name", "params": "h1"},
+		map[string]interface{}{"methodName": "system.multicall", "params": []interface{}{[]interface{}{}}},
+	}
+
+	res, err := newTestServer(t, torrentMethods).Call(context.Background(), "system.multicall", []interface{}{calls})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"faultCode": InvalidParams, "faultString": "call must be struct with members methodName and params"},
+		map[string]interface{}{"faultCode": InvalidParams, "faultString": "call must have string member methodName"},
+		map[string]interface{}{"faultCode": InvalidParams, "faultString": "params of call must be array"},
+		map[string]interface{}{"faultCode": InvalidParams, "faultString": "recursive system.multicall is not allowed"},
+	}, res)
+}
+
+func TestMulticallLimitsSize(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	s.MaxMulticallSize = 1
+	call := map[string]interface{}{"methodName": "d.name", "params": []interface{}{"h1"}}
+
+	_, err := s.Call(context.Background(), "system.multicall", []interface{}{[]interface{}{call, call}})
+	assert.Equal(t, NewFault(InvalidParams, "system.multicall accepts at most 1 calls, got 2"), err)
+
+	_, err = s.Call(context.Background(), "system.multicall", []interface{}{"d.name"})
+	assert.Equal(t, NewFault(InvalidParams, "system.multicall expects array of calls"), err)
+}
@@ -0,0 +1,296 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func dialSCGI(addr string) (net.Conn, error) {
+	if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		return net.Dial("unix", addr)
+	}
+	return net.Dial("tcp", addr)
+}
+
+func writeSCGIRequest(w io.Writer, body []byte) (err error) {
+	if err = writeSCGIRequestHeader(w, int64(len(body))); err != nil {
+		return
+	}
+	_, err = w.Write(body)
+	return
+}
+
+// writeSCGIRequestHeader writes header of request which body has the given length, body must be written next.
+func writeSCGIRequestHeader(w io.Writer, length int64) (err error) {
+	header := &bytes.Buffer{}
+	writeSCGIHeader(header, "CONTENT_LENGTH", strconv.FormatInt(length, 10))
+	writeSCGIHeader(header, "SCGI", "1")
+	writeSCGIHeader(header, "REQUEST_METHOD", "POST")
+	writeSCGIHeader(header, "SERVER_PROTOCOL", "HTTP/1.1")
+	if _, err = fmt.Fprintf(w, "%d:", header.Len()); err != nil {
+		return
+	}
+	if _, err = header.WriteTo(w); err != nil {
+		return
+	}
+	_, err = io.WriteString(w, ",")
+	return
+}
+
+func writeSCGIHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteByte(0)
+	buf.WriteString(value)
+	buf.WriteByte(0)
+}
+
+// readSCGIResponse reads CGI style response headers and returns reader of the response body.
+func readSCGIResponse(r *bufio.Reader) (body io.Reader, err error) {
+	header := make(map[string]string)
+	for {
+		var line string
+		if line, err = r.ReadString('\n'); err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			err = errors.New(fmt.Sprintf("invalid response header line: %q", line))
+			return
+		}
+		header[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	if status, ok := header["status"]; ok && !strings.HasPrefix(status, "200") {
+		err = errors.New(fmt.Sprintf("unexpected response status: %s", status))
+		return
+	}
+	body = r
+	if cl, ok := header["content-length"]; ok {
+		var n int64
+		if n, err = strconv.ParseInt(cl, 10, 64); err != nil {
+			err = errors.New(fmt.Sprintf("invalid content length: %s", cl))
+			return
+		}
+		body = &limitedByteReader{r: r, n: n}
+	}
+	return
+}
+
+// limitedByteReader is like io.LimitedReader but also implements io.ByteReader,
+// so xml.Decoder reads from it without additional buffering.
+type limitedByteReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (l *limitedByteReader) Read(p []byte) (n int, err error) {
+	if l.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[0:l.n]
+	}
+	n, err = l.r.Read(p)
+	l.n -= int64(n)
+	return
+}
+
+func (l *limitedByteReader) ReadByte() (b byte, err error) {
+	if l.n <= 0 {
+		return 0, io.EOF
+	}
+	if b, err = l.r.ReadByte(); err == nil {
+		l.n--
+	}
+	return
+}
+
+// maxSCGIHeaderBytes restricts size of SCGI request headers.
+const maxSCGIHeaderBytes = 1 << 16
+
+// scgiHeaderTimeout restricts time of reading SCGI request headers, so silent clients don't hold connections.
+const scgiHeaderTimeout = 30 * time.Second
+
+// ListenAndServeSCGI listens on network "tcp" or "unix" and serves SCGI requests, see ServeSCGI.
+func (s *Server) ListenAndServeSCGI(network, addr string) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return s.ServeSCGI(l)
+}
+
+// ServeSCGI accepts SCGI connections on l and serves XML-RPC call sent with each of them.
+// It returns error when l fails to accept connection, or ErrServerClosed after Shutdown.
+func (s *Server) ServeSCGI(l net.Listener) error {
+	if !s.track(l) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.untrack(l)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.trackConn(conn) {
+			writeSCGIError(conn, "503 Service Unavailable", "server is shutting down")
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveSCGIConn(conn)
+	}
+}
+
+func (s *Server) serveSCGIConn(conn net.Conn) {
+	defer s.untrackConn(conn)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(scgiHeaderTimeout))
+	header, err := readSCGIHeader(r)
+	if err != nil {
+		if !s.isClosed() {
+			writeSCGIError(conn, "400 Bad Request", err.Error())
+		}
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	// connection is in progress only when request is read, so Shutdown doesn't wait for silent clients
+	if !s.beginConn(conn) {
+		writeSCGIError(conn, "503 Service Unavailable", "server is shutting down")
+		return
+	}
+	defer s.end()
+	var n int64
+	if n, err = scgiContentLength(header); err != nil {
+		writeSCGIError(conn, "400 Bad Request", err.Error())
+		return
+	}
+	if max := s.Limits.MaxBodyBytes; max > 0 && n > max {
+		writeSCGIError(conn, "413 Request Entity Too Large", fmt.Sprintf("body must not be larger than %d bytes", max))
+		return
+	}
+	var ctx context.Context
+	if ctx, err = s.authenticate(context.Background(), scgiCredentials(header)); err != nil {
+		writeSCGIError(conn, "401 Unauthorized", err.Error(), "WWW-Authenticate: "+challengeOf(err))
+		return
+	}
+	body := &bytes.Buffer{}
+	if err = s.ServeCall(ctx, &limitedByteReader{r: r, n: n}, body); err != nil {
+		writeSCGIError(conn, "500 Internal Server Error", err.Error())
+		return
+	}
+	if _, err = fmt.Fprintf(conn, "Status: 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n",
+		s.contentType(), body.Len()); err != nil {
+		return
+	}
+	body.WriteTo(conn)
+}
+
+// readSCGIHeader reads headers of SCGI request encoded as netstring, e.g. "70:CONTENT_LENGTH\x0027\x00...,"
+func readSCGIHeader(r *bufio.Reader) (header map[string]string, err error) {
+	// length is read byte by byte, so client can't send endless length
+	var size []byte
+	for {
+		var b byte
+		if b, err = r.ReadByte(); err != nil {
+			err = errors.New("invalid scgi request, missing header length")
+			return
+		}
+		if b == ':' {
+			break
+		}
+		if b < '0' || b > '9' || len(size) == len(strconv.Itoa(maxSCGIHeaderBytes)) {
+			err = errors.New(fmt.Sprintf("invalid scgi request, invalid header length: %q", append(size, b)))
+			return
+		}
+		size = append(size, b)
+	}
+	var n int
+	if n, err = strconv.Atoi(string(size)); err != nil || len(size) > 1 && size[0] == '0' {
+		err = errors.New(fmt.Sprintf("invalid scgi request, invalid header length: %q", size))
+		return
+	}
+	if n > maxSCGIHeaderBytes {
+		err = errors.New(fmt.Sprintf("invalid scgi request, header must not be larger than %d bytes", maxSCGIHeaderBytes))
+		return
+	}
+	data := make([]byte, n+1)
+	if _, err = io.ReadFull(r, data); err != nil {
+		err = errors.New("invalid scgi request, unexpected end of header")
+		return
+	}
+	if data[n] != ',' {
+		err = errors.New("invalid scgi request, header must end with ','")
+		return
+	}
+	fields := strings.Split(string(data[:n]), "\x00")
+	if len(fields)%2 != 1 || fields[len(fields)-1] != "" {
+		err = errors.New("invalid scgi request, each header name must have value")
+		return
+	}
+	fields = fields[:len(fields)-1]
+	if len(fields) == 0 || fields[0] != "CONTENT_LENGTH" {
+		err = errors.New("invalid scgi request, first header must be CONTENT_LENGTH")
+		return
+	}
+	header = make(map[string]string, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		if _, dup := header[fields[i]]; dup {
+			err = errors.New(fmt.Sprintf("invalid scgi request, duplicate header %s", fields[i]))
+			return
+		}
+		header[fields[i]] = fields[i+1]
+	}
+	if header["SCGI"] != "1" {
+		err = errors.New("invalid scgi request, header SCGI must be 1")
+		return
+	}
+	return
+}
+
+func scgiContentLength(header map[string]string) (n int64, err error) {
+	cl := header["CONTENT_LENGTH"]
+	if n, err = strconv.ParseInt(cl, 10, 64); err != nil || n < 0 {
+		err = errors.New(fmt.Sprintf("invalid scgi request, invalid CONTENT_LENGTH: %q", cl))
+	}
+	return
+}
+
+// scgiCredentials builds credentials from CGI variables, e.g. HTTP_AUTHORIZATION and REMOTE_USER.
+func scgiCredentials(header map[string]string) *Credentials {
+	c := &Credentials{Header: make(http.Header), RemoteUser: header["REMOTE_USER"]}
+	for name, value := range header {
+		if strings.HasPrefix(name, "HTTP_") {
+			c.Header.Set(strings.Replace(name[len("HTTP_"):], "_", "-", -1), value)
+		}
+	}
+	return c
+}
+
+// writeSCGIError writes response with the given status, text and extra header lines, e.g. "Name: value".
+func writeSCGIError(w io.Writer, status, text string, header ...string) {
+	extra := ""
+	for _, h := range header {
+		extra += h + "\r\n"
+	}
+	fmt.Fprintf(w, "Status: %s\r\n%sContent-Type: text/plain\r\nContent-Length: %d\r\n\r\n%s",
+		status, extra, len(text), text)
+}
@@ -0,0 +1,274 @@
+package xmlrpc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSCGIRequest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := writeSCGIRequest(buf, []byte("<methodCall/>"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "70:CONTENT_LENGTH\x0013\x00SCGI\x001\x00REQUEST_METHOD\x00POST\x00SERVER_PROTOCOL\x00HTTP/1.1\x00,<methodCall/>", buf.String())
+}
+
+func TestReadSCGIResponseLimitsBodyToContentLength(t *testing.T) {
+	resp := "Status: 200 OK\r\nContent-Type: text/xml\r\nContent-Length: 5\r\n\r\nhello, trailing garbage"
+
+	body, err := readSCGIResponse(bufio.NewReader(strings.NewReader(resp)))
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(body)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestReadSCGIResponseFailsOnErrorStatus(t *testing.T) {
+	resp := "Status: 500 Internal Server Error\r\n\r\n"
+
+	_, err := readSCGIResponse(bufio.NewReader(strings.NewReader(resp)))
+	assert.Equal(t, "unexpected response status: 500 Internal Server Error", err.Error())
+}
+
+func TestSCGIClientSend(t *testing.T) {
+	addr, received := serveSCGIOnce(t, `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><array><data>
+            <value><string>ubuntu.iso</string></value>
+        </data></array></value></param>
+    </params>
+</methodResponse>`)
+
+	res, err := CreateSCGIClient(addr).Send("download_list")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"ubuntu.iso"}}, res)
+	assert.Contains(t, string(<-received), "<methodName>download_list</methodName>")
+}
+
+func TestSCGIClientSendArray(t *testing.T) {
+	addr, _ := serveSCGIOnce(t, multicallResponse)
+
+	it, err := (&SCGIXmlRpc{Addr: addr}).SendArray(nil, "d.multicall2", "", "main", "d.name=", "d.size_bytes=", "d.complete=")
+	assert.Nil(t, err)
+	defer it.Close()
+
+	var names []string
+	for it.Next() {
+		var row [3]interface{}
+		assert.Nil(t, it.Decode(&row))
+		names = append(names, row[0].(string))
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"ubuntu.iso", "debian.iso", "fedora.iso"}, names)
+}
+
+const okResponse = `<?xml version="1.0"?><methodResponse><params><param><value><i4>0</i4></value></param></params></methodResponse>`
+
+// waitingReader returns content only after ready is closed, its size is known without reading.
+type waitingReader struct {
+	r     *strings.Reader
+	ready chan struct{}
+}
+
+func (r *waitingReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.ready:
+		return r.r.Read(p)
+	case <-time.After(time.Second):
+		return 0, errors.New("content is read before request is sent")
+	}
+}
+
+func (r *waitingReader) Len() int {
+	return r.r.Len()
+}
+
+func TestSCGIClientStreamsBase64(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	addr, received, headerRead := serveSCGIOnceWithHeader(t, okResponse)
+
+	stream := Base64Stream{&waitingReader{strings.NewReader(content), headerRead}}
+
+	_, err := CreateSCGIClient(addr).Send("load.raw", "", stream)
+
+	if !assert.Nil(t, err) {
+		return
+	}
+	var data []byte
+	_, err = NewDecoder(bytes.NewReader(<-received)).DecodeCallInto(new(string), &data)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestSCGIClientSendsBase64(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	f, err := ioutil.TempFile("", "xmlrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	io.WriteString(f, "skipped"+content)
+	f.Seek(int64(len("skipped")), io.SeekStart)
+
+	tests := []struct {
+		name   string
+		stream io.Reader
+	}{
+		{"file", f},
+		// size of reader isn't known, so request is buffered
+		{"unknown size", io.MultiReader(strings.NewReader(content[:10]), strings.NewReader(content[10:]))},
+	}
+	for _, test := range tests {
+		addr, received := serveSCGIOnce(t, okResponse)
+
+		_, err := CreateSCGIClient(addr).Send("load.raw", "", Base64Stream{test.stream})
+
+		assert.Nil(t, err, test.name)
+		var data []byte
+		_, err = NewDecoder(bytes.NewReader(<-received)).DecodeCallInto(new(string), &data)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, content, string(data), test.name)
+	}
+}
+
+func TestServeSCGIOnTCP(t *testing.T) {
+	l := serveSCGI(t, newTestServer(t, torrentMethods), "tcp", "127.0.0.1:0")
+	defer l.Close()
+
+	res, err := CreateSCGIClient(l.Addr().String()).Send("d.name", "hash")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"ubuntu.iso"}, res)
+}
+
+func TestServeSCGIOnUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xmlrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l := serveSCGI(t, newTestServer(t, torrentMethods), "unix", filepath.Join(dir, "rpc.socket"))
+	defer l.Close()
+
+	res, err := CreateSCGIClient(filepath.Join(dir, "rpc.socket")).Send("d.name", "h1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"ubuntu.iso"}, res)
+
+	_, err = CreateSCGIClient(filepath.Join(dir, "rpc.socket")).Send("d.size")
+	assert.True(t, IsMethodNotFound(err))
+}
+
+func TestServeSCGIRejectsInvalidRequests(t *testing.T) {
+	s := newTestServer(t, torrentMethods)
+	s.Limits = Limits{MaxBodyBytes: 1000}
+	l := serveSCGI(t, s, "tcp", "127.0.0.1:0")
+	defer l.Close()
+
+	tests := []struct {
+		request string
+		status  string
+	}{
+		{"abc:", "400 Bad Request"},
+		{"01:x,", "400 Bad Request"},
+		{"24:CONTENT_LENGTH\x000\x00SCGI\x001\x00;", "400 Bad Request"},
+		{"11:SCGI\x001\x00X\x001\x00,", "400 Bad Request"},
+		{"25:CONTENT_LENGTH\x00-1\x00SCGI\x001\x00,", "400 Bad Request"},
+		{"25:CONTENT_LENGTH\x00ab\x00SCGI\x001\x00,", "400 Bad Request"},
+		{"17:CONTENT_LENGTH\x000\x00,", "400 Bad Request"},
+		{"24:CONTENT_LENGTH\x000\x00SCGI\x002\x00,", "400 Bad Request"},
+		{"27:CONTENT_LENGTH\x001001\x00SCGI\x001\x00,", "413 Request Entity Too Large"},
+	}
+	for _, test := range tests {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, test.request)
+		resp, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Close()
+
+		assert.Equal(t, "Status: "+test.status+"\r\n", resp, test.request)
+	}
+}
+
+func TestReadSCGIHeader(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("41:CONTENT_LENGTH\x0013\x00SCGI\x001\x00REMOTE_USER\x00joe\x00,<methodCall/>"))
+
+	header, err := readSCGIHeader(r)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"CONTENT_LENGTH": "13", "SCGI": "1", "REMOTE_USER": "joe"}, header)
+	rest, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "<methodCall/>", string(rest))
+}
+
+// digits is endless stream of digits.
+type digits struct{}
+
+func (digits) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = '1'
+	}
+	return len(p), nil
+}
+
+func TestReadSCGIHeaderRejectsLongLength(t *testing.T) {
+	_, err := readSCGIHeader(bufio.NewReader(digits{}))
+	assert.Equal(t, `invalid scgi request, invalid header length: "111111"`, err.Error())
+
+	_, err = readSCGIHeader(bufio.NewReader(strings.NewReader("65537:")))
+	assert.Equal(t, "invalid scgi request, header must not be larger than 65536 bytes", err.Error())
+}
+
+func serveSCGIOnce(t *testing.T, response string) (addr string, received chan []byte) {
+	addr, received, _ = serveSCGIOnceWithHeader(t, response)
+	return
+}
+
+// serveSCGIOnceWithHeader is serveSCGIOnce which closes headerRead when header of request is read.
+func serveSCGIOnceWithHeader(t *testing.T, response string) (addr string, received chan []byte,
+	headerRead chan struct{}) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received = make(chan []byte, 1)
+	headerRead = make(chan struct{})
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		size, _ := r.ReadString(':')
+		n, _ := strconv.Atoi(strings.TrimSuffix(size, ":"))
+		header := make([]byte, n+1)
+		io.ReadFull(r, header)
+		fields := strings.Split(string(header), "\x00")
+		cl, _ := strconv.Atoi(fields[1])
+		close(headerRead)
+		body := make([]byte, cl)
+		io.ReadFull(r, body)
+		received <- body
+		io.WriteString(conn, "Status: 200 OK\r\nContent-Type: text/xml\r\nContent-Length: "+strconv.Itoa(len(response))+"\r\n\r\n"+response)
+	}()
+	return l.Addr().String(), received, headerRead
+}
@@ -0,0 +1,329 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler handles method call with decoded params, returned value is sent as the only param of response.
+// Handler reports invalid params with *Fault with code InvalidParams, other errors are sent as faults too.
+type Handler func(ctx context.Context, params []interface{}) (interface{}, error)
+
+// Server dispatches XML-RPC calls to registered handlers.
+type Server struct {
+	// Limits restricts calls accepted by server.
+	Limits Limits
+	// Mode defines how strictly server follows the XML-RPC specification when reads calls.
+	Mode Mode
+	// Charset of responses, default is UTF-8, see Encoder.SetCharset.
+	Charset string
+	// InvalidChars defines how strings that contain characters not allowed in XML are sent,
+	// see Encoder.SetInvalidCharPolicy.
+	InvalidChars InvalidCharPolicy
+	// MaxMulticallSize is max number of calls in system.multicall, zero means no limit.
+	MaxMulticallSize int
+	// MapError converts errors returned by handlers to faults, it returns nil for unknown errors. Unknown errors
+	// are logged and sent as fault with code ApplicationError and generic text, *Fault is sent as is.
+	MapError func(err error) *Fault
+	// ErrorLog logs unknown errors and panics of handlers, if nil the standard logger is used.
+	ErrorLog *log.Logger
+	// MaxConcurrentCalls is max number of calls handled at the same time, zero means no limit. Other calls wait
+	// for their turn. It must not be changed after server started.
+	MaxConcurrentCalls int
+	// Timeout is default timeout of handlers, it's delivered to handlers as deadline of context. Zero means
+	// no timeout, see WithTimeout.
+	Timeout time.Duration
+	// Authenticators identify callers of HTTP and SCGI requests, the first authenticator which recognizes
+	// credentials wins. Identity of caller is available to handlers with IdentityFrom.
+	Authenticators []Authenticator
+	// ACL restricts access to methods, nil means all methods are allowed to everyone.
+	ACL ACL
+
+	mu           sync.RWMutex
+	methods      map[string]*method
+	methodsOnce  sync.Once
+	interceptors []Interceptor
+	closed       bool
+	listeners    map[net.Listener]struct{}
+	conns        map[net.Conn]bool // accepted SCGI connections, true if their request is in progress
+	inFlight     sync.WaitGroup
+	slots        chan struct{}
+	slotsOnce    sync.Once
+}
+
+// method is registered handler with its description for introspection.
+type method struct {
+	h Handler
+	// signatures of method, the first element of signature is type of result, the rest are types of params
+	signatures [][]string
+	help       string
+	timeout    time.Duration
+}
+
+// NewServer creates server which supports introspection methods system.listMethods, system.methodSignature
+// and system.methodHelp, system.multicall and system.getCapabilities. Server accepts <nil/> values in calls
+// and sends nil values and nil pointers returned by handlers as <nil/>. Zero value of Server is the same
+// server, so it may be configured with composite literal too.
+func NewServer() *Server {
+	s := &Server{}
+	s.initMethods()
+	return s
+}
+
+// initMethods registers system methods once, it must be called before methods are accessed.
+func (s *Server) initMethods() {
+	s.methodsOnce.Do(func() {
+		s.methods = make(map[string]*method)
+		s.registerIntrospection()
+		s.registerMulticall()
+		s.registerCapabilities()
+	})
+}
+
+// MethodOption describes registered method.
+type MethodOption func(*method)
+
+// WithSignature adds signature of method returned by system.methodSignature, the first type is type of result,
+// the rest are types of params, e.g. WithSignature("string", "string", "int"). Types are names of XML-RPC
+// types: i4, int, i8, boolean, string, base64, array and struct.
+func WithSignature(types ...string) MethodOption {
+	return func(m *method) {
+		m.signatures = append(m.signatures, types)
+	}
+}
+
+// WithHelp sets description of method returned by system.methodHelp.
+func WithHelp(help string) MethodOption {
+	return func(m *method) {
+		m.help = help
+	}
+}
+
+// WithTimeout sets timeout of method which overrides Server.Timeout.
+func WithTimeout(d time.Duration) MethodOption {
+	return func(m *method) {
+		m.timeout = d
+	}
+}
+
+// Register adds handler of method, method can't be registered twice.
+func (s *Server) Register(name string, h Handler, opts ...MethodOption) error {
+	if h == nil {
+		return errors.New(fmt.Sprintf("nil handler of method %s", name))
+	}
+	m := &method{h: h}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return s.register(map[string]*method{name: m})
+}
+
+// register adds all methods or none of them if any method is invalid or already registered.
+func (s *Server) register(methods map[string]*method) error {
+	for method := range methods {
+		if err := validateMethodName(method); err != nil {
+			return err
+		}
+	}
+	s.initMethods()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for method := range methods {
+		if _, ok := s.methods[method]; ok {
+			return errors.New(fmt.Sprintf("method %s is already registered", method))
+		}
+	}
+	for name, m := range methods {
+		s.methods[name] = m
+	}
+	return nil
+}
+
+// Call invokes handler of method through interceptors. It returns *Fault with code MethodNotFound if method
+// isn't registered and *Fault with code AccessDenied if ACL doesn't allow caller to call it. Panic of handler
+// or interceptor is logged and returned as *Fault with code InternalError.
+func (s *Server) Call(ctx context.Context, name string, params []interface{}) (result interface{}, err error) {
+	defer s.recoverFault(name, &err)
+	s.mu.RLock()
+	interceptors := s.interceptors
+	s.mu.RUnlock()
+	h := func(ctx context.Context, params []interface{}) (result interface{}, err error) {
+		// recovered here too, so interceptors see fault instead of panic
+		defer s.recoverFault(name, &err)
+		if err = s.authorize(ctx, name); err != nil {
+			return
+		}
+		var m *method
+		if m, err = s.lookup(name); err != nil {
+			return
+		}
+		timeout := m.timeout
+		if timeout == 0 {
+			timeout = s.Timeout
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return m.h(ctx, params)
+	}
+	if len(interceptors) == 0 {
+		return h(ctx, params)
+	}
+	return ChainInterceptors(interceptors...)(ctx, name, params, h)
+}
+
+// recoverFault converts panic to fault, it must be deferred.
+func (s *Server) recoverFault(name string, err *error) {
+	if r := recover(); r != nil {
+		s.logf("xmlrpc: panic in method %s: %v\n%s", name, r, debug.Stack())
+		*err = NewFault(InternalError, "internal error")
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+func (s *Server) lookup(name string) (*method, error) {
+	s.initMethods()
+	s.mu.RLock()
+	m, ok := s.methods[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, NewFault(MethodNotFound, fmt.Sprintf("method not found: %s", name))
+	}
+	return m, nil
+}
+
+// ServeCall reads <methodCall> document from r, dispatches it and writes <methodResponse> document to w.
+// Failures of decoding call or handling it are written as <fault>, returned error is the failure of writing
+// response.
+func (s *Server) ServeCall(ctx context.Context, r io.Reader, w io.Writer) error {
+	method, params, err := s.decodeCall(r)
+	return s.respond(ctx, w, method, params, err)
+}
+
+func (s *Server) decodeCall(r io.Reader) (method string, params []interface{}, err error) {
+	dec := NewDecoder(r)
+	dec.SetLimits(s.Limits)
+	dec.SetMode(s.Mode)
+	return dec.DecodeCall()
+}
+
+// respond dispatches decoded call and writes response to w, decodeErr is failure of decoding call.
+func (s *Server) respond(ctx context.Context, w io.Writer, method string, params []interface{},
+	decodeErr error) (err error) {
+	var result interface{}
+	if decodeErr != nil {
+		err = decodeFault(decodeErr)
+	} else if err = s.acquire(ctx); err != nil {
+		err = NewFault(SystemError, "server is busy")
+	} else {
+		result, err = s.Call(ctx, method, params)
+		s.release()
+	}
+	if err != nil {
+		return s.writeFault(w, s.faultOf(err))
+	}
+	// response is encoded into buffer, so fault can be sent instead if result can't be encoded
+	buf := &bytes.Buffer{}
+	var enc *Encoder
+	if enc, err = s.encoder(buf); err != nil {
+		return err
+	}
+	if err = enc.EncodeResponse(result); err != nil {
+		// error may contain the result, so it's only logged
+		s.logf("xmlrpc: cannot encode response of method %s: %v", method, err)
+		return s.writeFault(w, NewFault(InternalError, "internal error"))
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func (s *Server) writeFault(w io.Writer, f *Fault) error {
+	enc, err := s.encoder(w)
+	if err != nil {
+		return err
+	}
+	return enc.EncodeFault(f)
+}
+
+func (s *Server) encoder(w io.Writer) (*Encoder, error) {
+	enc := NewEncoder(w)
+	if err := enc.SetCharset(s.Charset); err != nil {
+		return nil, err
+	}
+	enc.SetInvalidCharPolicy(s.InvalidChars)
+	// server advertises capability "nil"
+	enc.SetNil(true)
+	return enc, nil
+}
+
+// contentType returns media type of responses with their charset.
+func (s *Server) contentType() string {
+	name := "UTF-8"
+	if cs, _ := lookupCharset(s.Charset); cs != nil {
+		name = cs.name
+	}
+	return "text/xml; charset=" + name
+}
+
+// faultOf converts error returned by handler to fault, see MapError.
+func (s *Server) faultOf(err error) *Fault {
+	if f, ok := err.(*Fault); ok {
+		return f
+	}
+	if s.MapError != nil {
+		if f := s.MapError(err); f != nil {
+			return f
+		}
+	}
+	if err == context.DeadlineExceeded {
+		return NewFault(SystemError, "timeout")
+	}
+	s.logf("xmlrpc: %v", err)
+	return NewFault(ApplicationError, "application error")
+}
+
+// isBodyTooLarge checks if failure of decoding is caused by body larger than Limits.MaxBodyBytes.
+func isBodyTooLarge(err error) bool {
+	if de, ok := err.(*DecodeError); ok {
+		err = de.Err
+	}
+	le, ok := err.(*LimitExceeded)
+	return ok && le.Limit == "MaxBodyBytes"
+}
+
+// decodeFault converts failure of decoding call to fault with interoperable code.
+func decodeFault(err error) *Fault {
+	cause := err
+	if de, ok := err.(*DecodeError); ok {
+		cause = de.Err
+	}
+	switch c := cause.(type) {
+	case *unsupportedCharset:
+		return NewFault(UnsupportedEncoding, err.Error())
+	case *xml.SyntaxError:
+		if strings.HasPrefix(c.Msg, "illegal character") || strings.HasPrefix(c.Msg, "invalid UTF-8") {
+			return NewFault(InvalidCharacter, err.Error())
+		}
+		return NewFault(ParseError, err.Error())
+	}
+	return NewFault(InvalidXmlRpc, err.Error())
+}
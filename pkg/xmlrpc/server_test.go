@@ -0,0 +1,225 @@
+package xmlrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// torrentMethods are methods of test servers: d.name expects hash and returns name of torrent, d.open fails with
+// unknown error.
+var torrentMethods = map[string]Handler{
+	"d.name": func(ctx context.Context, params []interface{}) (interface{}, error) {
+		if len(params) != 1 {
+			return nil, NewFault(InvalidParams, "expected hash")
+		}
+		return "ubuntu.iso", nil
+	},
+	"d.open": func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return nil, errors.New("no such torrent")
+	},
+}
+
+// newTestServer returns server with the given methods which doesn't log errors.
+func newTestServer(t *testing.T, methods map[string]Handler) *Server {
+	s := NewServer()
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	for name, h := range methods {
+		assert.Nil(t, s.Register(name, h))
+	}
+	return s
+}
+
+// serveSCGI serves SCGI requests to s until the returned listener is closed.
+func serveSCGI(t *testing.T, s *Server, network, addr string) net.Listener {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeSCGI(l)
+	return l
+}
+
+func serve(t *testing.T, s *Server, call string) (params []interface{}, err error) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, s.ServeCall(context.Background(), strings.NewReader(call), buf))
+	return NewDecoder(buf).DecodeResponse()
+}
+
+func TestServerDispatchesCall(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.Register("d.name", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return strings.ToUpper(params[0].(string)), nil
+	}))
+	call := `<?xml version="1.0"?>
+<methodCall><methodName>d.name</methodName><params><param><value>ubuntu.iso</value></param></params></methodCall>`
+
+	res, err := serve(t, s, call)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"UBUNTU.ISO"}, res)
+}
+
+func TestZeroServer(t *testing.T) {
+	s := &Server{Limits: Limits{MaxDepth: 10}}
+	assert.Nil(t, s.Register("d.name", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return "ubuntu.iso", nil
+	}))
+
+	res, err := serve(t, s, `<methodCall><methodName>d.name</methodName></methodCall>`)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"ubuntu.iso"}, res)
+	res, err = serve(t, &Server{}, `<methodCall><methodName>system.listMethods</methodName></methodCall>`)
+	assert.Nil(t, err)
+	assert.Contains(t, res[0], "system.multicall")
+}
+
+func TestServerRegisterRejectsInvalidAndDuplicateMethods(t *testing.T) {
+	s := NewServer()
+	h := func(ctx context.Context, params []interface{}) (interface{}, error) { return nil, nil }
+	assert.Nil(t, s.Register("d.name", h))
+	assert.Equal(t, "method d.name is already registered", s.Register("d.name", h).Error())
+	assert.Equal(t, `invalid method name "d name", character ' ' is not allowed`, s.Register("d name", h).Error())
+	assert.Equal(t, "nil handler of method d.size", s.Register("d.size", nil).Error())
+}
+
+func TestServerFaults(t *testing.T) {
+	s := NewServer()
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	assert.Nil(t, s.Register("d.open", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		if len(params) != 1 {
+			return nil, NewFault(InvalidParams, "expected hash")
+		}
+		return nil, errors.New("no such torrent")
+	}))
+	assert.Nil(t, s.Register("d.info", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return 1.5, nil
+	}))
+
+	tests := []struct {
+		call string
+		code int
+	}{
+		{`<methodCall><methodName>d.close</methodName></methodCall>`, MethodNotFound},
+		{`<methodCall><methodName>d.open</methodName></methodCall>`, InvalidParams},
+		{`<methodCall><methodName>d.open</methodName><params><param><value>x</value></param></params></methodCall>`,
+			ApplicationError},
+		{`<methodCall><methodName>d.info</methodName></methodCall>`, InternalError},
+		{`<methodCall><methodName>d.open</methodName><params>`, ParseError},
+		{"<methodCall><methodName>d.open\x00</methodName></methodCall>", InvalidCharacter},
+		{`<?xml version="1.0" encoding="KOI8-R"?><methodCall></methodCall>`, UnsupportedEncoding},
+		{`<methodResponse><params></params></methodResponse>`, InvalidXmlRpc},
+	}
+	for _, test := range tests {
+		_, err := serve(t, s, test.call)
+		f, ok := err.(*Fault)
+		if assert.True(t, ok, test.call) {
+			assert.Equal(t, test.code, f.Code, test.call)
+		}
+	}
+}
+
+func TestServerHidesUnknownErrors(t *testing.T) {
+	s := NewServer()
+	logged := &bytes.Buffer{}
+	s.ErrorLog = log.New(logged, "", 0)
+	assert.Nil(t, s.Register("d.open", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return nil, errors.New("open /var/lib/torrents/h1: permission denied")
+	}))
+
+	_, err := serve(t, s, `<methodCall><methodName>d.open</methodName></methodCall>`)
+
+	assert.Equal(t, NewFault(ApplicationError, "application error"), err)
+	assert.Equal(t, "xmlrpc: open /var/lib/torrents/h1: permission denied\n", logged.String())
+}
+
+func TestServerHidesEncodingErrors(t *testing.T) {
+	s := NewServer()
+	s.InvalidChars = RejectInvalidChars
+	logged := &bytes.Buffer{}
+	s.ErrorLog = log.New(logged, "", 0)
+	assert.Nil(t, s.Register("d.name", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return "secret\x00", nil
+	}))
+
+	_, err := serve(t, s, `<methodCall><methodName>d.name</methodName></methodCall>`)
+
+	assert.Equal(t, NewFault(InternalError, "internal error"), err)
+	assert.Contains(t, logged.String(), "xmlrpc: cannot encode response of method d.name: ")
+	assert.Contains(t, logged.String(), "secret")
+}
+
+var errNoTorrent = errors.New("no such torrent")
+
+func TestServerMapsErrors(t *testing.T) {
+	s := NewServer()
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	s.MapError = func(err error) *Fault {
+		if err == errNoTorrent {
+			return NewFault(1, "no such torrent")
+		}
+		return nil
+	}
+	assert.Nil(t, s.Register("d.open", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		if len(params) == 0 {
+			return nil, errNoTorrent
+		}
+		return nil, errors.New("disk failure")
+	}))
+
+	_, err := serve(t, s, `<methodCall><methodName>d.open</methodName></methodCall>`)
+	assert.Equal(t, NewFault(1, "no such torrent"), err)
+
+	_, err = serve(t, s, `<methodCall><methodName>d.open</methodName><params><param><value>h1</value></param></params></methodCall>`)
+	assert.Equal(t, NewFault(ApplicationError, "application error"), err)
+}
+
+func TestServerRecoversPanic(t *testing.T) {
+	s := NewServer()
+	logged := &bytes.Buffer{}
+	s.ErrorLog = log.New(logged, "", 0)
+	assert.Nil(t, s.Register("d.open", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		var m map[string]int
+		m["h1"] = 1
+		return nil, nil
+	}))
+	assert.Nil(t, s.Register("d.name", func(ctx context.Context, params []interface{}) (interface{}, error) {
+		return "ubuntu.iso", nil
+	}))
+
+	_, err := serve(t, s, `<methodCall><methodName>d.open</methodName></methodCall>`)
+
+	assert.Equal(t, NewFault(InternalError, "internal error"), err)
+	assert.Contains(t, logged.String(), "xmlrpc: panic in method d.open: assignment to entry in nil map")
+	assert.Contains(t, logged.String(), "runtime/debug.Stack")
+
+	calls := []interface{}{
+		map[string]interface{}{"methodName": "d.open", "params": []interface{}{}},
+		map[string]interface{}{"methodName": "d.name", "params": []interface{}{}},
+	}
+	res, err := s.Call(context.Background(), "system.multicall", []interface{}{calls})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"faultCode": InternalError, "faultString": "internal error"},
+		[]interface{}{"ubuntu.iso"},
+	}, res)
+}
+
+func TestServerRecoversPanicOfInterceptor(t *testing.T) {
+	s := NewServer()
+	s.ErrorLog = log.New(ioutil.Discard, "", 0)
+	s.Use(func(ctx context.Context, method string, params []interface{}, next Handler) (interface{}, error) {
+		panic("broken interceptor")
+	})
+
+	_, err := s.Call(context.Background(), "system.listMethods", nil)
+
+	assert.Equal(t, NewFault(InternalError, "internal error"), err)
+}
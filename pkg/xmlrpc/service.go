@@ -0,0 +1,218 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode"
+)
+
+// NameCase defines how names of Go methods are converted to names of XML-RPC methods.
+type NameCase int
+
+const (
+	// LowerCamelCase converts e.g. GetHTTPStatus to getHTTPStatus, it's default.
+	LowerCamelCase NameCase = iota
+	// SnakeCase converts e.g. GetHTTPStatus to get_http_status.
+	SnakeCase
+	// KeepCase uses names of Go methods as is.
+	KeepCase
+)
+
+// ServiceOption configures RegisterService.
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	nameCase NameCase
+	exclude  map[string]bool
+	help     map[string]string
+	timeout  map[string]time.Duration
+}
+
+// WithNameCase sets how names of methods of service are converted.
+func WithNameCase(c NameCase) ServiceOption {
+	return func(o *serviceOptions) {
+		o.nameCase = c
+	}
+}
+
+// WithMethodHelp sets description of Go method with the given name returned by system.methodHelp.
+func WithMethodHelp(name, help string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.help[name] = help
+	}
+}
+
+// WithMethodTimeout sets timeout of Go method with the given name, see WithTimeout.
+func WithMethodTimeout(name string, d time.Duration) ServiceOption {
+	return func(o *serviceOptions) {
+		o.timeout[name] = d
+	}
+}
+
+// ExcludeMethods excludes Go methods with the given names from service.
+func ExcludeMethods(names ...string) ServiceOption {
+	return func(o *serviceOptions) {
+		for _, n := range names {
+			o.exclude[n] = true
+		}
+	}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterService registers each suitable exported method of receiver as XML-RPC method namespace.name,
+// or just name if namespace is empty. Suitable method may accept context.Context as the first parameter
+// and must return a result and an error, e.g.
+//
+//	func (t *Torrents) Name(ctx context.Context, hash string) (string, error)
+//
+// Params of call are decoded into parameters of method, call with wrong number or types of params fails with
+// fault InvalidParams. Non-nil error returned by method is sent as fault. Signatures of methods reported by
+// system.methodSignature are inferred from their types.
+func (s *Server) RegisterService(receiver interface{}, namespace string, opts ...ServiceOption) error {
+	o := &serviceOptions{
+		exclude: make(map[string]bool),
+		help:    make(map[string]string),
+		timeout: make(map[string]time.Duration),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	rv := reflect.ValueOf(receiver)
+	if !rv.IsValid() {
+		return errors.New(fmt.Sprintf("nil receiver of service %s", namespace))
+	}
+	t := rv.Type()
+	methods := make(map[string]*method)
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.PkgPath != "" || o.exclude[m.Name] || !isSuitableMethod(m.Type) {
+			continue
+		}
+		name := convertName(m.Name, o.nameCase)
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		methods[name] = &method{
+			h:          methodHandler(name, rv.Method(i)),
+			signatures: [][]string{methodSignature(rv.Method(i).Type())},
+			help:       o.help[m.Name],
+			timeout:    o.timeout[m.Name],
+		}
+	}
+	if len(methods) == 0 {
+		return errors.New(fmt.Sprintf("type %T has no suitable methods", receiver))
+	}
+	return s.register(methods)
+}
+
+// isSuitableMethod checks type of method, the receiver is the first parameter of t.
+func isSuitableMethod(t reflect.Type) bool {
+	return !t.IsVariadic() && t.NumOut() == 2 && t.Out(1) == errorType
+}
+
+func methodHandler(name string, m reflect.Value) Handler {
+	t := m.Type()
+	withContext := t.NumIn() > 0 && t.In(0) == contextType
+	first := 0
+	if withContext {
+		first = 1
+	}
+	return func(ctx context.Context, params []interface{}) (interface{}, error) {
+		if len(params) != t.NumIn()-first {
+			return nil, NewFault(InvalidParams,
+				fmt.Sprintf("method %s expects %d params, got %d", name, t.NumIn()-first, len(params)))
+		}
+		args := make([]reflect.Value, t.NumIn())
+		if withContext {
+			args[0] = reflect.ValueOf(&ctx).Elem()
+		}
+		for i, p := range params {
+			arg := reflect.New(t.In(first + i)).Elem()
+			if err := convertValue(arg, p); err != nil {
+				return nil, NewFault(InvalidParams, fmt.Sprintf("invalid param %d of method %s: %v", i, name, err))
+			}
+			args[first+i] = arg
+		}
+		out := m.Call(args)
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	}
+}
+
+// methodSignature infers signature of method, see WithSignature.
+func methodSignature(t reflect.Type) []string {
+	sig := []string{xmlrpcType(t.Out(0))}
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && t.In(i) == contextType {
+			continue
+		}
+		sig = append(sig, xmlrpcType(t.In(i)))
+	}
+	return sig
+}
+
+// xmlrpcType returns name of XML-RPC type of values of t, or "undef" if values may be of any type.
+func xmlrpcType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32:
+		return "int"
+	case reflect.Int64, reflect.Uint64:
+		return "i8"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "base64"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "struct"
+	}
+	return "undef"
+}
+
+func convertName(name string, c NameCase) string {
+	switch c {
+	case LowerCamelCase:
+		r := []rune(name)
+		for i := 0; i < len(r) && unicode.IsUpper(r[i]); i++ {
+			// keep the last capital of acronym which starts the next word, e.g. HTTPStatus -> httpStatus
+			if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+				break
+			}
+			r[i] = unicode.ToLower(r[i])
+		}
+		return string(r)
+	case SnakeCase:
+		r := []rune(name)
+		var out []rune
+		for i, c := range r {
+			if unicode.IsUpper(c) {
+				if i > 0 && (!unicode.IsUpper(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+					out = append(out, '_')
+				}
+				c = unicode.ToLower(c)
+			}
+			out = append(out, c)
+		}
+		return string(out)
+	}
+	return name
+}
@@ -0,0 +1,150 @@
+package xmlrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type torrentFile struct {
+	Path string `xmlrpc:"path"`
+	Size int64  `xmlrpc:"size"`
+}
+
+type torrents struct {
+	names map[string]string
+}
+
+func (t *torrents) Name(ctx context.Context, hash string) (string, error) {
+	if n, ok := t.names[hash]; ok {
+		return n, nil
+	}
+	return "", errors.New("no such torrent")
+}
+
+func (t *torrents) SetFiles(hash string, files []torrentFile, priority uint8) (int, error) {
+	return len(files) + int(priority), nil
+}
+
+func (t *torrents) GetHTTPStatus() (string, error) {
+	return "ok", nil
+}
+
+func (t *torrents) Close() error {
+	return nil
+}
+
+func (t *torrents) Variadic(hashes ...string) (int, error) {
+	return len(hashes), nil
+}
+
+func TestRegisterService(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.RegisterService(&torrents{names: map[string]string{"h1": "ubuntu.iso"}}, "d"))
+	ctx := context.Background()
+
+	res, err := s.Call(ctx, "d.name", []interface{}{"h1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ubuntu.iso", res)
+
+	_, err = s.Call(ctx, "d.name", []interface{}{"h2"})
+	assert.Equal(t, "no such torrent", err.Error())
+
+	files := []interface{}{map[string]interface{}{"path": "a", "size": 1}, map[string]interface{}{"path": "b"}}
+	res, err = s.Call(ctx, "d.setFiles", []interface{}{"h1", files, 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, res)
+
+	res, err = s.Call(ctx, "d.getHTTPStatus", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", res)
+
+	_, err = s.Call(ctx, "d.close", nil)
+	assert.True(t, IsMethodNotFound(err))
+	_, err = s.Call(ctx, "d.variadic", nil)
+	assert.True(t, IsMethodNotFound(err))
+}
+
+func TestRegisterServiceChecksParams(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.RegisterService(&torrents{}, "d"))
+	ctx := context.Background()
+
+	_, err := s.Call(ctx, "d.name", nil)
+	assert.Equal(t, NewFault(InvalidParams, "method d.name expects 1 params, got 0"), err)
+
+	_, err = s.Call(ctx, "d.name", []interface{}{1})
+	assert.Equal(t, NewFault(InvalidParams, "invalid param 0 of method d.name: cannot decode int into string"), err)
+
+	_, err = s.Call(ctx, "d.setFiles", []interface{}{"h1", []interface{}{}, 256})
+	assert.Equal(t, NewFault(InvalidParams, "invalid param 2 of method d.setFiles: value 256 overflows uint8"), err)
+}
+
+type echoService struct{}
+
+func (echoService) Echo(v interface{}) (interface{}, error) {
+	return v, nil
+}
+
+func (echoService) Size(size *int64, file torrentFile) (int64, error) {
+	if size == nil {
+		return file.Size, nil
+	}
+	return *size, nil
+}
+
+func TestRegisterServiceAcceptsNil(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.RegisterService(echoService{}, "e"))
+	ctx := context.Background()
+
+	res, err := s.Call(ctx, "e.echo", []interface{}{nil})
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+
+	file := map[string]interface{}{"path": nil, "size": 2}
+	res, err = s.Call(ctx, "e.size", []interface{}{nil, file})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), res)
+}
+
+func TestRegisterServiceOptions(t *testing.T) {
+	s := NewServer()
+	err := s.RegisterService(&torrents{}, "", WithNameCase(SnakeCase), ExcludeMethods("Name", "SetFiles"))
+	assert.Nil(t, err)
+
+	res, err := s.Call(context.Background(), "get_http_status", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", res)
+	_, err = s.Call(context.Background(), "name", []interface{}{"h1"})
+	assert.True(t, IsMethodNotFound(err))
+}
+
+func TestRegisterServiceFails(t *testing.T) {
+	s := NewServer()
+	assert.Nil(t, s.RegisterService(&torrents{}, "d"))
+	assert.Equal(t, "method d.getHTTPStatus is already registered",
+		s.RegisterService(&torrents{}, "d", ExcludeMethods("Name", "SetFiles")).Error())
+	assert.Equal(t, "type *xmlrpc.torrents has no suitable methods",
+		s.RegisterService(&torrents{}, "t", ExcludeMethods("Name", "SetFiles", "GetHTTPStatus")).Error())
+	assert.Equal(t, "nil receiver of service t", s.RegisterService(nil, "t").Error())
+}
+
+func TestConvertName(t *testing.T) {
+	tests := []struct {
+		name, lowerCamel, snake string
+	}{
+		{"Name", "name", "name"},
+		{"SetFiles", "setFiles", "set_files"},
+		{"GetHTTPStatus", "getHTTPStatus", "get_http_status"},
+		{"HTTPStatus", "httpStatus", "http_status"},
+		{"ID", "id", "id"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.lowerCamel, convertName(test.name, LowerCamelCase))
+		assert.Equal(t, test.snake, convertName(test.name, SnakeCase))
+		assert.Equal(t, test.name, convertName(test.name, KeepCase))
+	}
+}
@@ -0,0 +1,230 @@
+package xmlrpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var writerType = reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+// targetOf returns value that v points to, v must be a non-nil pointer.
+func targetOf(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}, errors.New(fmt.Sprintf("decode target must be a non-nil pointer, got %T", v))
+	}
+	return rv.Elem(), nil
+}
+
+func targetsOf(vs []interface{}) (tv []reflect.Value, err error) {
+	tv = make([]reflect.Value, len(vs))
+	for i, v := range vs {
+		if tv[i], err = targetOf(v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// asWriter returns io.Writer if target is or holds a writer, such targets receive content of <base64> as stream.
+func asWriter(v reflect.Value) (io.Writer, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() && v.Elem().Type().Implements(writerType) {
+			return v.Elem().Interface().(io.Writer), true
+		}
+		return nil, false
+	case reflect.Ptr:
+		if !v.IsNil() && v.Type().Implements(writerType) {
+			return v.Interface().(io.Writer), true
+		}
+		return nil, false
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(writerType) {
+		return v.Addr().Interface().(io.Writer), true
+	}
+	return nil, false
+}
+
+// indirect allocates nil pointers and returns the value they point to.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmptyInterface(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Interface && v.NumMethod() == 0
+}
+
+// assignValue sets decoded scalar value v to target converting it to type of target if possible.
+func assignValue(target reflect.Value, v interface{}) (err error) {
+	if !target.IsValid() {
+		return
+	}
+	if v == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return
+	}
+	sv := reflect.ValueOf(v)
+	switch target.Kind() {
+	case reflect.Interface:
+		if sv.Type().Implements(target.Type()) {
+			target.Set(sv)
+			return
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if sv.Kind() == reflect.Int || sv.Kind() == reflect.Int64 {
+			if target.OverflowInt(sv.Int()) {
+				return errors.New(fmt.Sprintf("value %d overflows %v", sv.Int(), target.Type()))
+			}
+			target.SetInt(sv.Int())
+			return
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if sv.Kind() == reflect.Int || sv.Kind() == reflect.Int64 {
+			if sv.Int() < 0 || target.OverflowUint(uint64(sv.Int())) {
+				return errors.New(fmt.Sprintf("value %d overflows %v", sv.Int(), target.Type()))
+			}
+			target.SetUint(uint64(sv.Int()))
+			return
+		}
+	case reflect.Float32, reflect.Float64:
+		if sv.Kind() == reflect.Int || sv.Kind() == reflect.Int64 {
+			target.SetFloat(float64(sv.Int()))
+			return
+		}
+	case reflect.String:
+		if sv.Kind() == reflect.String {
+			target.SetString(sv.String())
+			return
+		}
+	case reflect.Bool:
+		if sv.Kind() == reflect.Bool {
+			target.SetBool(sv.Bool())
+			return
+		}
+	case reflect.Slice:
+		if b, ok := v.([]byte); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+			target.SetBytes(b)
+			return
+		}
+	}
+	return errors.New(fmt.Sprintf("cannot decode %T into %v", v, target.Type()))
+}
+
+// structField finds field of struct st for member name. Returns invalid value if there is no such field.
+func structField(st reflect.Value, name string) reflect.Value {
+	t := st.Type()
+	fallback := -1
+	for i := 0; i < t.NumField(); i++ {
+		fn, ok := fieldName(t.Field(i))
+		if !ok {
+			continue
+		}
+		if fn == name {
+			return st.Field(i)
+		}
+		if fallback < 0 && strings.EqualFold(fn, name) {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return st.Field(fallback)
+	}
+	return reflect.Value{}
+}
+
+// fieldName returns name of struct member for the field, field name may be overridden with tag `xmlrpc:"name"`.
+// ok is false if the field must not be encoded or decoded.
+func fieldName(f reflect.StructField) (name string, ok bool) {
+	if f.PkgPath != "" {
+		return
+	}
+	name = f.Name
+	if tag, found := f.Tag.Lookup("xmlrpc"); found {
+		if tag == "-" {
+			return
+		}
+		if tag != "" {
+			name = tag
+		}
+	}
+	ok = true
+	return
+}
+
+// convertValue sets value v produced by decoding into interface{} to target, it converts v the same way
+// as decoding directly into target does.
+func convertValue(target reflect.Value, v interface{}) (err error) {
+	if v == nil {
+		// <nil/> sets zero value as decoding does, nil pointer isn't allocated
+		target.Set(reflect.Zero(target.Type()))
+		return
+	}
+	target = indirect(target)
+	switch vv := v.(type) {
+	case []interface{}:
+		switch {
+		case isEmptyInterface(target):
+			target.Set(reflect.ValueOf(vv))
+			return
+		case target.Kind() == reflect.Slice:
+			s := reflect.MakeSlice(target.Type(), len(vv), len(vv))
+			for i, e := range vv {
+				if err = convertValue(s.Index(i), e); err != nil {
+					return
+				}
+			}
+			target.Set(s)
+			return
+		case target.Kind() == reflect.Array:
+			for i := 0; i < len(vv) && i < target.Len(); i++ {
+				if err = convertValue(target.Index(i), vv[i]); err != nil {
+					return
+				}
+			}
+			return
+		}
+		return errors.New(fmt.Sprintf("cannot decode array into %v", target.Type()))
+	case map[string]interface{}:
+		switch {
+		case isEmptyInterface(target):
+			target.Set(reflect.ValueOf(vv))
+			return
+		case target.Kind() == reflect.Map && target.Type().Key().Kind() == reflect.String:
+			if target.IsNil() {
+				target.Set(reflect.MakeMap(target.Type()))
+			}
+			for n, e := range vv {
+				mt := reflect.New(target.Type().Elem()).Elem()
+				if err = convertValue(mt, e); err != nil {
+					return
+				}
+				target.SetMapIndex(reflect.ValueOf(n).Convert(target.Type().Key()), mt)
+			}
+			return
+		case target.Kind() == reflect.Struct:
+			for n, e := range vv {
+				if f := structField(target, n); f.IsValid() {
+					if err = convertValue(f, e); err != nil {
+						return
+					}
+				}
+			}
+			return
+		}
+		return errors.New(fmt.Sprintf("cannot decode struct into %v", target.Type()))
+	}
+	return assignValue(target, v)
+}
@@ -7,49 +7,78 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
 type unmarshaller struct {
-	last *xml.Token
+	// token which is read but not consumed yet
+	unreadToken xml.Token
+	limits      Limits
+	mode        Mode
+	depth       int
+	// path of the value being decoded, e.g. params[0][1532].struct["d.name"]
+	path []string
 }
 
-type valueElement struct {
-	Data string `xml:",chardata"`
-}
+const any = ""
 
-type fault struct {
-	code int
-	text string
+func (u *unmarshaller) unmarshal(b []byte) (params []interface{}, err error) {
+	return u.unmarshalResponse(xml.NewDecoder(bytes.NewReader(b)))
 }
 
-const any = ""
+func (u *unmarshaller) unmarshalResponse(d *xml.Decoder) (params []interface{}, err error) {
+	err = u.unmarshalResponseTo(d, appendParam(&params))
+	return
+}
 
-func (u *unmarshaller) unmarshal(b []byte) (params []interface{}, err error) {
-	d := xml.NewDecoder(bytes.NewReader(b))
+// unmarshalResponseTo reads <methodResponse> and decodes each param into value returned by target.
+func (u *unmarshaller) unmarshalResponseTo(d *xml.Decoder, target func(i int) reflect.Value) (err error) {
 	var se *xml.StartElement
-	if se, err = u.startElement(d, "methodResponse"); err != nil || se == nil {
+	if se, err = u.startElement(d, "methodResponse"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing element methodResponse")
 		return
 	}
 	if se, err = u.startElement(d, any); err != nil {
 		return
 	}
+	if se == nil {
+		err = errors.New("invalid xml, missing params or fault")
+		return
+	}
 	name := se.Name.Local
 	switch name {
 	case "params":
-		if params, err = u.unmarshalParams(d); err != nil {
+		u.push("params")
+		n := 0
+		counted := func(i int) reflect.Value {
+			n++
+			return target(i)
+		}
+		if err = u.unmarshalParams(d, counted); err != nil {
+			return
+		}
+		u.pop()
+		if u.mode == Strict && n != 1 {
+			err = errors.New(fmt.Sprintf("response must contain exactly one param, got %d", n))
 			return
 		}
 		_, err = u.mustEndElement(d, "params")
 	case "fault":
-		var f fault
+		u.push("fault")
+		var f *Fault
 		if f, err = u.unmarshalFault(d); err != nil {
 			return
 		}
+		u.pop()
 		if _, err = u.mustEndElement(d, "fault"); err != nil {
 			return
 		}
-		err = errors.New(fmt.Sprintf("error response, code: %d, text: %s", f.code, f.text))
+		err = f
 	default:
 		err = errors.New(fmt.Sprintf("invalid xml, unknown element %s", name))
 		return
@@ -60,67 +89,368 @@ func (u *unmarshaller) unmarshal(b []byte) (params []interface{}, err error) {
 	return
 }
 
-func (u *unmarshaller) unmarshalParams(d *xml.Decoder) (params []interface{}, err error) {
+func (u *unmarshaller) unmarshalCall(d *xml.Decoder) (method string, params []interface{}, err error) {
+	method, err = u.unmarshalCallTo(d, appendParam(&params))
+	return
+}
+
+// unmarshalCallTo reads <methodCall> and decodes each param into value returned by target.
+func (u *unmarshaller) unmarshalCallTo(d *xml.Decoder, target func(i int) reflect.Value) (method string, err error) {
 	var se *xml.StartElement
-	for {
+	if se, err = u.startElement(d, "methodCall"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing element methodCall")
+		return
+	}
+	if se, err = u.startElement(d, "methodName"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing element methodName")
+		return
+	}
+	if method, err = u.text(d); err != nil {
+		return
+	}
+	if err = validateMethodName(method); err != nil {
+		return
+	}
+	if se, err = u.startElement(d, "params"); err != nil {
+		return
+	}
+	if se != nil {
+		u.push("params")
+		if err = u.unmarshalParams(d, target); err != nil {
+			return
+		}
+		u.pop()
+		if _, err = u.mustEndElement(d, "params"); err != nil {
+			return
+		}
+	}
+	_, err = u.mustEndElement(d, "methodCall")
+	return
+}
+
+// seekResponseArray reads <methodResponse> up to the elements of the array found at path.
+func (u *unmarshaller) seekResponseArray(d *xml.Decoder, path []interface{}) (err error) {
+	var se *xml.StartElement
+	if len(path) == 0 {
+		path = []interface{}{0}
+	}
+	idx, ok := path[0].(int)
+	if !ok {
+		err = errors.New(fmt.Sprintf("invalid path, first element must be index of param: %v", path[0]))
+		return
+	}
+	if se, err = u.startElement(d, "methodResponse"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing element methodResponse")
+		return
+	}
+	if se, err = u.startElement(d, any); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing params or fault")
+		return
+	}
+	switch se.Name.Local {
+	case "params":
+		u.push("params")
+	case "fault":
+		u.push("fault")
+		var f *Fault
+		if f, err = u.unmarshalFault(d); err == nil {
+			err = f
+		}
+		return
+	default:
+		err = errors.New(fmt.Sprintf("invalid xml, unknown element %s", se.Name.Local))
+		return
+	}
+	for i := 0; ; i++ {
+		if se, err = u.startElement(d, "param"); err != nil {
+			return
+		}
+		if se == nil {
+			err = errors.New(fmt.Sprintf("no param with index %d", idx))
+			return
+		}
+		u.push(index(i))
+		if i == idx {
+			break
+		}
+		if _, err = u.unmarshalValueTo(d, reflect.Value{}); err != nil {
+			return
+		}
+		u.pop()
+		if _, err = u.mustEndElement(d, "param"); err != nil {
+			return
+		}
+	}
+	for _, p := range path[1:] {
+		if err = u.seekElement(d, p); err != nil {
+			return
+		}
+	}
+	if se, err = u.startElement(d, "value"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New(fmt.Sprintf("no value at path %v", path))
+		return
+	}
+	if se, err = u.startElement(d, "array"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New(fmt.Sprintf("value at path %v is not an array", path))
+		return
+	}
+	if se, err = u.startElement(d, "data"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New("invalid xml, missing element data")
+	}
+	return
+}
+
+// seekElement reads container value up to its element or member p.
+func (u *unmarshaller) seekElement(d *xml.Decoder, p interface{}) (err error) {
+	var se *xml.StartElement
+	if se, err = u.startElement(d, "value"); err != nil {
+		return
+	}
+	if se == nil {
+		err = errors.New(fmt.Sprintf("no value for path element %v", p))
+		return
+	}
+	switch k := p.(type) {
+	case int:
+		if se, err = u.startElement(d, "array"); err != nil {
+			return
+		}
+		if se == nil {
+			err = errors.New(fmt.Sprintf("value for path element %d is not an array", k))
+			return
+		}
+		if se, err = u.startElement(d, "data"); err != nil {
+			return
+		}
+		if se == nil {
+			err = errors.New("invalid xml, missing element data")
+			return
+		}
+		for i := 0; i < k; i++ {
+			u.push(index(i))
+			var ok bool
+			if ok, err = u.unmarshalValueTo(d, reflect.Value{}); err != nil {
+				return
+			}
+			u.pop()
+			if !ok {
+				err = errors.New(fmt.Sprintf("no array element with index %d", k))
+				return
+			}
+		}
+		u.push(index(k))
+	case string:
+		if se, err = u.startElement(d, "struct"); err != nil {
+			return
+		}
+		if se == nil {
+			err = errors.New(fmt.Sprintf("value for path element %q is not a struct", k))
+			return
+		}
+		for {
+			if se, err = u.startElement(d, "member"); err != nil {
+				return
+			}
+			if se == nil {
+				err = errors.New(fmt.Sprintf("no struct member %q", k))
+				return
+			}
+			if se, err = u.startElement(d, "name"); err != nil {
+				return
+			}
+			if se == nil {
+				err = errors.New("invalid xml, missing element name")
+				return
+			}
+			var n string
+			if n, err = u.text(d); err != nil {
+				return
+			}
+			u.push(member(n))
+			if n == k {
+				return
+			}
+			if _, err = u.unmarshalValueTo(d, reflect.Value{}); err != nil {
+				return
+			}
+			u.pop()
+			if _, err = u.mustEndElement(d, "member"); err != nil {
+				return
+			}
+		}
+	default:
+		err = errors.New(fmt.Sprintf("invalid path element: %v", p))
+	}
+	return
+}
+
+func (u *unmarshaller) unmarshalParams(d *xml.Decoder, target func(i int) reflect.Value) (err error) {
+	var se *xml.StartElement
+	for i := 0; ; i++ {
 		if se, err = u.startElement(d, "param"); err != nil || se == nil {
 			return
 		}
-		var v interface{}
-		v, err = u.unmarshalValue(d)
-		if err != nil {
+		u.push(index(i))
+		if _, err = u.unmarshalValueTo(d, target(i)); err != nil {
 			return
 		}
-		params = append(params, v)
+		u.pop()
 		if _, err = u.mustEndElement(d, "param"); err != nil {
 			return
 		}
 	}
 }
 
-func (u *unmarshaller) unmarshalFault(d *xml.Decoder) (f fault, err error) {
+// appendParam returns param target that appends params to the slice.
+func appendParam(params *[]interface{}) func(i int) reflect.Value {
+	return func(i int) reflect.Value {
+		*params = append(*params, nil)
+		return reflect.ValueOf(*params).Index(i)
+	}
+}
+
+// paramTargets returns param target that decodes params into targets, params that have no target are skipped.
+func paramTargets(targets []reflect.Value) func(i int) reflect.Value {
+	return func(i int) reflect.Value {
+		if i < len(targets) {
+			return targets[i]
+		}
+		return reflect.Value{}
+	}
+}
+
+func (u *unmarshaller) unmarshalFault(d *xml.Decoder) (f *Fault, err error) {
 	var v interface{}
-	v, err = u.unmarshalValue(d)
+	if v, err = u.unmarshalValue(d); err != nil {
+		return
+	}
 	fm, ok := v.(map[string]interface{})
 	if !ok {
 		err = errors.New(fmt.Sprintf("unable parse fault message correctly: %v", v))
+		return
 	}
-	fcode, ok := fm["faultCode"]
+	code, ok := fm["faultCode"].(int)
 	if !ok {
 		err = errors.New(fmt.Sprintf("no code in fault message: %v", fm))
+		return
 	}
-	fmsg, ok := fm["faultString"]
+	text, ok := fm["faultString"].(string)
 	if !ok {
-		err = errors.New(fmt.Sprintf("no code in fault message: %v", fm))
+		err = errors.New(fmt.Sprintf("no text in fault message: %v", fm))
+		return
 	}
-	code := fcode.(int)
-	msg := fmsg.(string)
-	f = fault{code: code, text: msg}
+	f = NewFault(code, text)
 	return
 }
 
 func (u *unmarshaller) unmarshalValue(d *xml.Decoder) (v interface{}, err error) {
+	_, err = u.unmarshalValueTo(d, reflect.ValueOf(&v).Elem())
+	return
+}
+
+// unmarshalValueTo reads next <value> element into target, invalid target means the value is skipped.
+// ok is false if there is no more <value> elements.
+func (u *unmarshaller) unmarshalValueTo(d *xml.Decoder, target reflect.Value) (ok bool, err error) {
 	var se *xml.StartElement
-	var vn valueElement
 	if se, err = u.startElement(d, "value"); err != nil || se == nil {
 		return
 	}
-	if se, err = u.startElement(d, any); err != nil {
+	ok = true
+	var text []byte
+	for se = nil; se == nil; {
+		var t xml.Token
+		if t, err = u.next(d); err != nil {
+			return
+		}
+		switch e := t.(type) {
+		case xml.CharData:
+			if text, err = u.appendText(text, e); err != nil {
+				return
+			}
+		case xml.StartElement:
+			se = &e
+		case xml.EndElement:
+			// value without type is string
+			err = assignValue(indirect(target), string(text))
+			return
+		}
+	}
+	if err = u.skipText(text); err != nil {
 		return
 	}
 	name := se.Name.Local
+	if name == "nil" {
+		// extension, see http://ontosys.com/xml-rpc/extensions.php
+		if target.IsValid() {
+			target.Set(reflect.Zero(target.Type()))
+		}
+		if _, err = u.mustEndElement(d, "nil"); err == nil {
+			_, err = u.mustEndElement(d, "value")
+		}
+		return
+	}
+	if w, isWriter := asWriter(target); isWriter && name == "base64" {
+		if err = u.unmarshalBase64To(d, w); err == nil {
+			_, err = u.mustEndElement(d, "value")
+		}
+		return
+	}
+	target = indirect(target)
 	switch name {
-	case "string", "base64", "int", "i4", "i8", "boolean":
-		if err = d.DecodeElement(&vn, se); err != nil {
+	case "string", "int", "i4", "i8", "boolean":
+		var raw string
+		if raw, err = u.text(d); err != nil {
+			return
+		}
+		var v interface{}
+		if v, err = decodeValue(raw, name, u.mode); err != nil {
 			return
 		}
-		v, err = decodeValue(vn.Data, name)
-		u.last = nil
+		err = assignValue(target, v)
+	case "base64":
+		buf := &bytes.Buffer{}
+		if err = u.unmarshalBase64To(d, buf); err != nil {
+			return
+		}
+		err = assignValue(target, buf.Bytes())
 	case "array":
-		v, err = u.unmarshalArray(d)
+		if err = u.enter(); err != nil {
+			return
+		}
+		if err = u.unmarshalArray(d, target); err != nil {
+			return
+		}
+		u.depth--
 		_, err = u.mustEndElement(d, "array")
 	case "struct":
-		v, err = u.unmarshalStruct(d)
+		if err = u.enter(); err != nil {
+			return
+		}
+		if err = u.unmarshalStruct(d, target); err != nil {
+			return
+		}
+		u.depth--
 		_, err = u.mustEndElement(d, "struct")
 	default:
 		err = errors.New(fmt.Sprintf("unsupported type: %s", name))
@@ -131,127 +461,367 @@ func (u *unmarshaller) unmarshalValue(d *xml.Decoder) (v interface{}, err error)
 	return
 }
 
-func (u *unmarshaller) unmarshalArray(d *xml.Decoder) (arr []interface{}, err error) {
+func (u *unmarshaller) unmarshalArray(d *xml.Decoder, target reflect.Value) (err error) {
 	var se *xml.StartElement
 	if se, err = u.startElement(d, "data"); err != nil || se == nil {
 		return
 	}
-	for {
-		var v interface{}
-		v, err = u.unmarshalValue(d)
-		if v == nil {
-			break
+	if isEmptyInterface(target) {
+		var arr []interface{}
+		av := reflect.ValueOf(&arr).Elem()
+		if err = u.unmarshalElements(d, av); err != nil {
+			return
+		}
+		target.Set(av)
+	} else {
+		if err = u.unmarshalElements(d, target); err != nil {
+			return
 		}
-		arr = append(arr, v)
 	}
 	_, err = u.mustEndElement(d, "data")
 	return
 }
 
-func (u *unmarshaller) unmarshalStruct(d *xml.Decoder) (m map[string]interface{}, err error) {
+func (u *unmarshaller) unmarshalElements(d *xml.Decoder, target reflect.Value) (err error) {
+	var elem func(i int) reflect.Value
+	switch {
+	case !target.IsValid():
+		elem = func(i int) reflect.Value {
+			return target
+		}
+	case target.Kind() == reflect.Slice:
+		target.SetLen(0)
+		elem = func(i int) reflect.Value {
+			return reflect.New(target.Type().Elem()).Elem()
+		}
+	case target.Kind() == reflect.Array:
+		elem = func(i int) reflect.Value {
+			if i < target.Len() {
+				return target.Index(i)
+			}
+			return reflect.Value{}
+		}
+	default:
+		err = errors.New(fmt.Sprintf("cannot decode array into %v", target.Type()))
+		return
+	}
+	for i := 0; ; i++ {
+		if u.limits.MaxArrayLength > 0 && i >= u.limits.MaxArrayLength {
+			var se *xml.StartElement
+			if se, err = u.startElement(d, "value"); err == nil && se != nil {
+				err = &LimitExceeded{Limit: "MaxArrayLength", Max: int64(u.limits.MaxArrayLength)}
+			}
+			return
+		}
+		e := elem(i)
+		u.push(index(i))
+		var ok bool
+		if ok, err = u.unmarshalValueTo(d, e); err != nil {
+			return
+		}
+		u.pop()
+		if !ok {
+			return
+		}
+		if target.IsValid() && target.Kind() == reflect.Slice {
+			target.Set(reflect.Append(target, e))
+		}
+	}
+}
+
+func (u *unmarshaller) unmarshalStruct(d *xml.Decoder, target reflect.Value) (err error) {
 	var se *xml.StartElement
-	var vn valueElement
-	m = make(map[string]interface{})
-	for {
+	if isEmptyInterface(target) {
+		m := make(map[string]interface{})
+		mv := reflect.ValueOf(m)
+		if err = u.unmarshalStruct(d, mv); err != nil {
+			return
+		}
+		target.Set(mv)
+		return
+	}
+	if target.IsValid() {
+		switch target.Kind() {
+		case reflect.Map:
+			if target.Type().Key().Kind() != reflect.String {
+				err = errors.New(fmt.Sprintf("cannot decode struct into %v", target.Type()))
+				return
+			}
+			if target.IsNil() {
+				target.Set(reflect.MakeMap(target.Type()))
+			}
+		case reflect.Struct:
+		default:
+			err = errors.New(fmt.Sprintf("cannot decode struct into %v", target.Type()))
+			return
+		}
+	}
+	for count := 1; ; count++ {
 		if se, err = u.startElement(d, "member"); err != nil || se == nil {
 			return
 		}
+		if u.limits.MaxStructMembers > 0 && count > u.limits.MaxStructMembers {
+			err = &LimitExceeded{Limit: "MaxStructMembers", Max: int64(u.limits.MaxStructMembers)}
+			return
+		}
 		if se, err = u.startElement(d, "name"); err != nil {
 			return
 		}
-		if err = d.DecodeElement(&vn, se); err != nil {
+		if se == nil {
+			err = errors.New("invalid xml, missing element name")
 			return
 		}
-		n := vn.Data
-		u.last = nil
-		var v interface{}
-		if v, err = u.unmarshalValue(d); err != nil {
+		var n string
+		if n, err = u.text(d); err != nil {
+			return
+		}
+		var mt reflect.Value
+		switch {
+		case !target.IsValid():
+		case target.Kind() == reflect.Map:
+			mt = reflect.New(target.Type().Elem()).Elem()
+		default:
+			mt = structField(target, n)
+		}
+		u.push(member(n))
+		if _, err = u.unmarshalValueTo(d, mt); err != nil {
+			return
+		}
+		u.pop()
+		if target.IsValid() && target.Kind() == reflect.Map {
+			target.SetMapIndex(reflect.ValueOf(n).Convert(target.Type().Key()), mt)
+		}
+		if _, err = u.mustEndElement(d, "member"); err != nil {
 			return
 		}
-		m[n] = v
-		_, err = u.mustEndElement(d, "member")
 	}
 }
 
-func (u *unmarshaller) startElement(d *xml.Decoder, name string) (se *xml.StartElement, err error) {
-	var t xml.Token
-	if u.last != nil {
-		t = *u.last
-		u.last = nil
+func (u *unmarshaller) push(segment string) {
+	u.path = append(u.path, segment)
+}
+
+func (u *unmarshaller) pop() {
+	u.path = u.path[:len(u.path)-1]
+}
+
+func index(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+func member(name string) string {
+	return fmt.Sprintf(".struct[%q]", name)
+}
+
+func (u *unmarshaller) enter() error {
+	u.depth++
+	if u.limits.MaxDepth > 0 && u.depth > u.limits.MaxDepth {
+		return &LimitExceeded{Limit: "MaxDepth", Max: int64(u.limits.MaxDepth)}
 	}
-	for se == nil {
+	return nil
+}
+
+// text reads text content of the current element up to its end element. Text may be split with comments
+// and CDATA sections.
+func (u *unmarshaller) text(d *xml.Decoder) (s string, err error) {
+	var buf []byte
+	for {
+		var t xml.Token
+		if t, err = u.next(d); err != nil {
+			return
+		}
 		switch e := t.(type) {
+		case xml.CharData:
+			if buf, err = u.appendText(buf, e); err != nil {
+				return
+			}
 		case xml.StartElement:
-			if name == any || e.Name.Local == name {
-				se = &e
-			} else {
-				u.last = &t
+			if err = u.skipElement(d, e); err != nil {
+				return
 			}
-			return
 		case xml.EndElement:
-			u.last = &t
+			s = string(buf)
 			return
 		}
-		t, err = d.Token()
-		if t == nil && err == io.EOF {
-			err = nil
-			return
+	}
+}
+
+func (u *unmarshaller) appendText(buf []byte, data xml.CharData) ([]byte, error) {
+	buf = append(buf, data...)
+	if u.limits.MaxStringBytes > 0 && len(buf) > u.limits.MaxStringBytes {
+		return nil, &LimitExceeded{Limit: "MaxStringBytes", Max: int64(u.limits.MaxStringBytes)}
+	}
+	return buf, nil
+}
+
+// unmarshalBase64To decodes content of <base64> element into w without buffering of decoded data.
+func (u *unmarshaller) unmarshalBase64To(d *xml.Decoder, w io.Writer) (err error) {
+	lw := &limitedWriter{w: w, max: u.limits.MaxBase64Bytes, limit: "MaxBase64Bytes"}
+	_, err = io.Copy(lw, base64.NewDecoder(base64.StdEncoding, &charDataReader{u: u, d: d}))
+	return
+}
+
+// charDataReader reads text content of the current element up to its end element,
+// whitespaces are skipped.
+type charDataReader struct {
+	u    *unmarshaller
+	d    *xml.Decoder
+	data []byte
+	done bool
+}
+
+func (r *charDataReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(r.data) == 0 {
+			if r.done {
+				break
+			}
+			var t xml.Token
+			if t, err = r.u.next(r.d); err != nil {
+				return
+			}
+			switch e := t.(type) {
+			case xml.CharData:
+				r.data = e
+			case xml.EndElement:
+				r.done = true
+			case xml.StartElement:
+				if err = r.u.skipElement(r.d, e); err != nil {
+					return
+				}
+			}
+			continue
 		}
-		if err != nil {
+		c := r.data[0]
+		r.data = r.data[1:]
+		if r.u.mode == Strict && (c == ' ' || c == '\t') {
+			err = errors.New("invalid base64, only line breaks are allowed")
 			return
 		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			p[n] = c
+			n++
+		}
+	}
+	if n == 0 && r.done {
+		err = io.EOF
 	}
 	return
 }
 
-func (u *unmarshaller) endElement(d *xml.Decoder, name string) (ee *xml.EndElement, err error) {
-	var t xml.Token = u.last
-	if u.last != nil {
-		t = *u.last
-		u.last = nil
+// next returns next token of the document. Comments, processing instructions and directives are dropped,
+// content of CDATA sections is returned as regular text.
+func (u *unmarshaller) next(d *xml.Decoder) (t xml.Token, err error) {
+	if u.unreadToken != nil {
+		t, u.unreadToken = u.unreadToken, nil
+		return
 	}
-	for ee == nil {
-		switch e := t.(type) {
-		case xml.EndElement:
-			if name == any || e.Name.Local == name {
-				ee = &e
-			} else {
-				u.last = &t
-			}
+	for {
+		if t, err = d.Token(); err != nil {
 			return
-		case xml.StartElement:
-			u.last = &t
+		}
+		switch t.(type) {
+		case xml.StartElement, xml.EndElement, xml.CharData:
 			return
 		}
-		t, err = d.Token()
-		if t == nil && err == io.EOF {
-			err = nil
+	}
+}
+
+// unread puts token back, it's returned by the next call of next.
+func (u *unmarshaller) unread(t xml.Token) {
+	u.unreadToken = xml.CopyToken(t)
+}
+
+// skipElement skips unknown element which start is already read, it fails in strict mode.
+func (u *unmarshaller) skipElement(d *xml.Decoder, se xml.StartElement) error {
+	if u.mode == Strict {
+		return errors.New(fmt.Sprintf("invalid xml, unexpected element %s", se.Name.Local))
+	}
+	return d.Skip()
+}
+
+// skipText skips text between elements, it must be whitespaces only in strict mode.
+func (u *unmarshaller) skipText(data xml.CharData) error {
+	if u.mode == Strict && len(bytes.TrimSpace(data)) > 0 {
+		return errors.New(fmt.Sprintf("invalid xml, unexpected text %q", string(data)))
+	}
+	return nil
+}
+
+// startElement reads up to the next start element with the given name or any start element if name is any.
+// Unknown elements and text are skipped. It returns nil if end element or end of document found before, such
+// end element is left unread. In strict mode it returns nil if finds start element with another name, such
+// element is left unread.
+func (u *unmarshaller) startElement(d *xml.Decoder, name string) (se *xml.StartElement, err error) {
+	for {
+		var t xml.Token
+		if t, err = u.next(d); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
 			return
 		}
-		if err != nil {
+		switch e := t.(type) {
+		case xml.StartElement:
+			if name == any || e.Name.Local == name {
+				se = &e
+				return
+			}
+			if u.mode == Strict {
+				u.unread(e)
+				return
+			}
+			if err = d.Skip(); err != nil {
+				return
+			}
+		case xml.EndElement:
+			u.unread(e)
 			return
+		case xml.CharData:
+			if err = u.skipText(e); err != nil {
+				return
+			}
 		}
 	}
-	return
 }
 
+// mustEndElement reads up to the end element with the given name, unknown elements and text are skipped.
 func (u *unmarshaller) mustEndElement(d *xml.Decoder, name string) (ee *xml.EndElement, err error) {
-	if ee, err = u.endElement(d, name); err != nil {
-		return
-	}
-	if ee == nil {
-		err = errors.New(fmt.Sprintf("missing end element: %s", name))
-		return
+	for {
+		var t xml.Token
+		if t, err = u.next(d); err != nil {
+			if err == io.EOF {
+				err = errors.New(fmt.Sprintf("missing end element: %s", name))
+			}
+			return
+		}
+		switch e := t.(type) {
+		case xml.EndElement:
+			if e.Name.Local != name {
+				err = errors.New(fmt.Sprintf("missing end element: %s", name))
+				return
+			}
+			ee = &e
+			return
+		case xml.StartElement:
+			if err = u.skipElement(d, e); err != nil {
+				return
+			}
+		case xml.CharData:
+			if err = u.skipText(e); err != nil {
+				return
+			}
+		}
 	}
-	return
 }
 
-func decodeValue(raw string, t string) (v interface{}, err error) {
+func decodeValue(raw string, t string, mode Mode) (v interface{}, err error) {
+	if t != "string" && mode == Lenient {
+		raw = strings.TrimSpace(raw)
+	}
 	switch t {
 	case "string":
 		v = raw
-	case "base64":
-		v, err = base64.StdEncoding.DecodeString(raw)
 	case "i4", "int":
 		var i64 int64
 		if i64, err = strconv.ParseInt(raw, 10, 32); err == nil {
@@ -260,7 +830,18 @@ func decodeValue(raw string, t string) (v interface{}, err error) {
 	case "i8":
 		v, err = strconv.ParseInt(raw, 10, 64)
 	case "boolean":
-		v, err = strconv.ParseBool(raw)
+		if mode == Lenient {
+			v, err = strconv.ParseBool(raw)
+			return
+		}
+		switch raw {
+		case "0":
+			v = false
+		case "1":
+			v = true
+		default:
+			err = errors.New(fmt.Sprintf("invalid boolean: %q", raw))
+		}
 	}
 	return
 }
@@ -212,3 +212,102 @@ func TestUnmarshalFaultMessage(t *testing.T) {
 
 	assert.Equal(t, "error response, code: 3, text: something went wrong", err.Error())
 }
+
+func TestUnmarshalSkipsCommentsAndProcessingInstructions(t *testing.T) {
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE methodResponse>
+<!-- response of download_list -->
+<methodResponse>
+    <?server-hint fast?>
+    <params><!-- one param -->
+        <param><value><array><!-- rows --><data>
+            <value><!-- name --><string>hello<!-- split -->world</string></value>
+            <value><i4><!-- n -->123</i4></value>
+            <?ignored?>
+        </data></array></value></param>
+    </params>
+</methodResponse>
+<!-- end -->
+`)
+
+	u := unmarshaller{}
+	res, err := u.unmarshal(xml)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"helloworld", 123}}, res)
+}
+
+func TestUnmarshalCDATA(t *testing.T) {
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value><string><![CDATA[<b>bold</b> & co]]></string></value></param>
+        <param><value><string>a <![CDATA[<&>]]> b</string></value></param>
+        <param><value><![CDATA[untyped <cdata>]]></value></param>
+    </params>
+</methodResponse>
+`)
+
+	u := unmarshaller{}
+	res, err := u.unmarshal(xml)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"<b>bold</b> & co", "a <&> b", "untyped <cdata>"}, res)
+}
+
+func TestUnmarshalValueWithoutType(t *testing.T) {
+	xml := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse>
+    <params>
+        <param><value>  hello  </value></param>
+        <param><value></value></param>
+        <param><value/></param>
+        <param><value>
+            <i4>1</i4>
+        </value></param>
+    </params>
+</methodResponse>
+`)
+
+	u := unmarshaller{}
+	res, err := u.unmarshal(xml)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"  hello  ", "", "", 1}, res)
+}
+
+func TestUnmarshalKeepsWhitespacesInString(t *testing.T) {
+	xml := []byte("<methodResponse><params><param><value><string> \t two\n lines </string></value></param></params></methodResponse>")
+
+	u := unmarshaller{}
+	res, err := u.unmarshal(xml)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{" \t two\n lines "}, res)
+}
+
+func TestUnmarshalTextBetweenMembers(t *testing.T) {
+	xml := []byte(`<methodResponse><params><param><value><struct>
+    <member><name>a</name><value><i4>1</i4></value></member>
+    unexpected
+    <member><name>b</name> text <value><i4>2</i4></value></member>
+</struct></value></param></params></methodResponse>`)
+
+	lenient := unmarshaller{}
+	res, err := lenient.unmarshal(xml)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"a": 1, "b": 2}}, res)
+
+	strict := unmarshaller{mode: Strict}
+	_, err = strict.unmarshal(xml)
+	assert.Equal(t, `invalid xml, unexpected text "\n    unexpected\n    "`, err.Error())
+}
+
+func TestUnmarshalTextAroundTypedValueInStrictMode(t *testing.T) {
+	xml := []byte(`<methodResponse><params><param><value>oops<i4>1</i4></value></param></params></methodResponse>`)
+
+	u := unmarshaller{mode: Strict}
+	_, err := u.unmarshal(xml)
+
+	assert.Equal(t, `invalid xml, unexpected text "oops"`, err.Error())
+}